@@ -0,0 +1,142 @@
+// Package budget tracks what transcription has cost and enforces daily
+// and monthly spending limits.
+//
+// Spend is kept in a ledger separate from history, so pruning, clearing
+// or encrypting history doesn't reset it. The ledger holds one JSONL file
+// per calendar month, ~/.vox/spend/YYYY-MM.jsonl: limits only ever look
+// at the current month, and old months are never rewritten.
+package budget
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrExceeded is returned when a transcription would go over a limit.
+var ErrExceeded = errors.New("budget exceeded")
+
+// Charge is one line of the ledger: the estimated cost of one
+// transcription.
+type Charge struct {
+	Timestamp string  `json:"ts"` // RFC 3339
+	CostUSD   float64 `json:"cost_usd"`
+	DurationS float64 `json:"duration_s"`
+	Model     string  `json:"model,omitempty"`
+}
+
+// Limits are spending limits in US dollars. Zero means no limit.
+type Limits struct {
+	Daily   float64
+	Monthly float64
+}
+
+// IsZero reports whether no limit is set.
+func (l Limits) IsZero() bool {
+	return l.Daily == 0 && l.Monthly == 0
+}
+
+// Spend is what has been charged so far today and this month.
+type Spend struct {
+	Day   float64
+	Month float64
+}
+
+// Check returns an error wrapping ErrExceeded if spending cost more on
+// top of s would go over a limit.
+func (l Limits) Check(s Spend, cost float64) error {
+	if l.Daily > 0 && s.Day+cost > l.Daily {
+		return fmt.Errorf("%w: ~$%.2f would bring today's spend to $%.2f of the $%.2f daily budget", ErrExceeded, cost, s.Day+cost, l.Daily)
+	}
+	if l.Monthly > 0 && s.Month+cost > l.Monthly {
+		return fmt.Errorf("%w: ~$%.2f would bring this month's spend to $%.2f of the $%.2f monthly budget", ErrExceeded, cost, s.Month+cost, l.Monthly)
+	}
+	return nil
+}
+
+// Ledger records charges in a directory of monthly files.
+type Ledger struct {
+	dir string
+}
+
+// NewLedger returns a ledger kept in dir.
+func NewLedger(dir string) *Ledger {
+	return &Ledger{dir: dir}
+}
+
+// DefaultDir returns the default ledger directory: ~/.vox/spend.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", ".vox", "spend")
+	}
+	return filepath.Join(home, ".vox", "spend")
+}
+
+// monthFile returns the ledger file for the local month containing t.
+func (l *Ledger) monthFile(t time.Time) string {
+	return filepath.Join(l.dir, t.Local().Format("2006-01")+".jsonl")
+}
+
+// Record appends c to the file for the month it was charged in.
+func (l *Ledger) Record(c Charge) error {
+	t, err := time.Parse(time.RFC3339, c.Timestamp)
+	if err != nil {
+		return fmt.Errorf("charge timestamp: %w", err)
+	}
+	if err := os.MkdirAll(l.dir, 0o700); err != nil {
+		return err
+	}
+	line, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.monthFile(t), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Spent totals the charges made on now's local day and in its month.
+// Lines that don't parse are skipped.
+func (l *Ledger) Spent(now time.Time) (Spend, error) {
+	var s Spend
+	f, err := os.Open(l.monthFile(now))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+
+	now = now.Local()
+	y, m, d := now.Date()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var c Charge
+		if json.Unmarshal(sc.Bytes(), &c) != nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, c.Timestamp)
+		if err != nil {
+			continue
+		}
+		t = t.Local()
+		if ty, tm, _ := t.Date(); ty != y || tm != m {
+			continue
+		}
+		s.Month += c.CostUSD
+		if t.Day() == d {
+			s.Day += c.CostUSD
+		}
+	}
+	return s, sc.Err()
+}
@@ -0,0 +1,80 @@
+package budget
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerSpent(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLedger(dir)
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	for _, c := range []struct {
+		when string
+		cost float64
+	}{
+		{"2026-09-30 23:00", 5},
+		{"2026-10-01 08:00", 0.25},
+		{"2026-10-18 09:00", 0.5},
+		{"2026-10-19 07:00", 0.125},
+		{"2026-10-19 21:00", 1},
+	} {
+		if err := l.Record(Charge{Timestamp: at(c.when).UTC().Format(time.RFC3339), CostUSD: c.cost}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A damaged line is skipped.
+	f, _ := os.OpenFile(filepath.Join(dir, "2026-10.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("{\"ts\":\n")
+	f.Close()
+
+	s, err := l.Spent(at("2026-10-19 22:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Day != 1.125 || s.Month != 1.875 {
+		t.Errorf("Spent = %+v, want day 1.125, month 1.875", s)
+	}
+
+	s, err = l.Spent(at("2026-11-01 10:00"))
+	if err != nil || s != (Spend{}) {
+		t.Errorf("new month: Spent = %+v, %v; want nothing", s, err)
+	}
+}
+
+func TestLimitsCheck(t *testing.T) {
+	spent := Spend{Day: 0.75, Month: 9.5}
+	tests := []struct {
+		name   string
+		limits Limits
+		cost   float64
+		over   bool
+	}{
+		{"no limits", Limits{}, 100, false},
+		{"within daily", Limits{Daily: 1}, 0.25, false},
+		{"over daily", Limits{Daily: 1}, 0.3, true},
+		{"within both", Limits{Daily: 2, Monthly: 10}, 0.5, false},
+		{"over monthly", Limits{Daily: 2, Monthly: 10}, 0.6, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(spent, tt.cost)
+			if got := errors.Is(err, ErrExceeded); got != tt.over {
+				t.Errorf("Check = %v, want over budget %v", err, tt.over)
+			}
+		})
+	}
+	if !(Limits{}).IsZero() || (Limits{Monthly: math.SmallestNonzeroFloat64}).IsZero() {
+		t.Error("IsZero wrong")
+	}
+}
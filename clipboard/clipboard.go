@@ -25,13 +25,18 @@ func Detect() (string, error) {
 // Returns false on headless Linux (no DISPLAY or WAYLAND_DISPLAY)
 // or when no clipboard tool is installed.
 func Available() bool {
+	return Check() == nil
+}
+
+// Check returns why clipboard copy won't work, or nil if Available.
+func Check() error {
 	if runtime.GOOS == "linux" {
 		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
-			return false
+			return fmt.Errorf("no DISPLAY or WAYLAND_DISPLAY: no graphical session to copy to")
 		}
 	}
 	_, err := Detect()
-	return err == nil
+	return err
 }
 
 // Write copies text to the system clipboard using the detected clipboard tool.
@@ -2,6 +2,7 @@ package clipboard
 
 import (
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -57,3 +58,14 @@ func TestAvailableHeadlessLinux(t *testing.T) {
 		t.Error("expected Available()=false on headless linux")
 	}
 }
+
+func TestCheckHeadlessLinux(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("skipping: not running on linux")
+	}
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if err := Check(); err == nil || !strings.Contains(err.Error(), "DISPLAY") {
+		t.Errorf("Check() = %v, want it to name DISPLAY", err)
+	}
+}
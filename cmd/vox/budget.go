@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cdimoush/vox/budget"
+	"github.com/cdimoush/vox/config"
+	"github.com/cdimoush/vox/history"
+	"github.com/cdimoush/vox/transcribe"
+)
+
+// configLimits reads the budget_daily and budget_monthly settings (US
+// dollars).
+func configLimits() (budget.Limits, error) {
+	var l budget.Limits
+	var err error
+	if l.Daily, err = config.Dollars("budget_daily"); err != nil {
+		return l, err
+	}
+	if l.Monthly, err = config.Dollars("budget_monthly"); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// checkBudget estimates what transcribing the audio at path will cost and
+// refuses if that would go over a spending limit. ask reads the user's
+// answer; a nil ask (nobody to ask) means refusing, and yes means going
+// ahead regardless. Audio whose length can't be measured isn't checked.
+func checkBudget(path string, yes, quiet bool, ask func() (string, error)) error {
+	limits, err := configLimits()
+	if err != nil || limits.IsZero() {
+		return err
+	}
+	seconds, err := transcribe.GetDuration(path)
+	if err != nil {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "⚠ Could not estimate cost, budget not checked: %v\n", err)
+		}
+		return nil
+	}
+	spent, err := budget.NewLedger(budget.DefaultDir()).Spent(time.Now())
+	if err != nil {
+		return fmt.Errorf("reading spend: %w", err)
+	}
+	over := limits.Check(spent, transcribe.EstimateCost(transcribe.ConfiguredModel(), seconds))
+	if over == nil {
+		return nil
+	}
+	if yes {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "⚠ %v (continuing: --yes)\n", over)
+		}
+		return nil
+	}
+	if ask == nil {
+		return over
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠ %v\n", over)
+	fmt.Fprint(os.Stderr, "Transcribe anyway? [y/N] ")
+	response, err := ask()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(strings.ToLower(response)) != "y" {
+		return fmt.Errorf("%w: not sent", budget.ErrExceeded)
+	}
+	return nil
+}
+
+// recordSpend adds a transcription's estimated cost to the spend ledger.
+// Failing to record it is only worth a warning, which quiet (--json)
+// leaves out.
+func recordSpend(e history.Entry, quiet bool) {
+	err := budget.NewLedger(budget.DefaultDir()).Record(budget.Charge{
+		Timestamp: e.Timestamp,
+		CostUSD:   e.CostUSD,
+		DurationS: e.DurationS,
+		Model:     e.Model,
+	})
+	if err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "⚠ Could not record spend: %v\n", err)
+	}
+}
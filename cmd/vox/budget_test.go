@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cdimoush/vox/budget"
+)
+
+func TestConfigLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    budget.Limits
+		wantErr bool
+	}{
+		{"unset", "", budget.Limits{}, false},
+		{"both", "budget_daily=1.50\nbudget_monthly=$20\n", budget.Limits{Daily: 1.5, Monthly: 20}, false},
+		{"not a number", "budget_daily=lots\n", budget.Limits{}, true},
+		{"negative", "budget_monthly=-5\n", budget.Limits{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			os.MkdirAll(filepath.Join(home, ".vox"), 0o700)
+			os.WriteFile(filepath.Join(home, ".vox", "config"), []byte(tt.config), 0o600)
+
+			got, err := configLimits()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigLimitsSource(t *testing.T) {
+	// The error names the file the bad value is in.
+	t.Setenv("HOME", t.TempDir())
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	os.MkdirAll(filepath.Join(xdg, "vox"), 0o700)
+	os.WriteFile(filepath.Join(xdg, "vox", "config"), []byte("budget_monthly=lots\n"), 0o600)
+
+	want := "budget_monthly in " + filepath.Join(xdg, "vox", "config")
+	if _, err := configLimits(); err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("err = %v, want it to start %q", err, want)
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	// A fake soxi reports 10 minutes of audio: ~$0.03 at the default
+	// model's price, over a 1 cent daily budget.
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "soxi"), []byte("#!/bin/sh\necho 600\n"), 0o755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("VOX_PROFILE", "")
+	t.Setenv("VOX_MODEL", "")
+
+	answer := func(s string) func() (string, error) {
+		return func() (string, error) { return s, nil }
+	}
+	tests := []struct {
+		name     string
+		config   string
+		yes      bool
+		ask      func() (string, error) // nil: no terminal
+		wantAsk  bool
+		wantOver bool
+	}{
+		{"under budget", "budget_daily=1\n", false, answer("n"), false, false},
+		{"confirmed", "budget_daily=0.01\n", false, answer("y\n"), true, false},
+		{"declined", "budget_daily=0.01\n", false, answer("n\n"), true, true},
+		{"no answer", "budget_daily=0.01\n", false, answer(""), true, true},
+		{"--yes", "budget_daily=0.01\n", true, answer("n"), false, false},
+		{"no terminal", "budget_daily=0.01\n", false, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			os.MkdirAll(filepath.Join(home, ".vox"), 0o700)
+			os.WriteFile(filepath.Join(home, ".vox", "config"), []byte(tt.config), 0o600)
+
+			asked := false
+			var ask func() (string, error)
+			if tt.ask != nil {
+				ask = func() (string, error) {
+					asked = true
+					return tt.ask()
+				}
+			}
+			err := checkBudget("memo.m4a", tt.yes, true, ask)
+			if asked != tt.wantAsk {
+				t.Errorf("asked = %v, want %v", asked, tt.wantAsk)
+			}
+			if got := errors.Is(err, budget.ErrExceeded); got != tt.wantOver {
+				t.Fatalf("checkBudget = %v, want over budget %v", err, tt.wantOver)
+			}
+			if tt.wantOver && exitCode(err) != 4 {
+				t.Errorf("exit code = %d, want 4", exitCode(err))
+			}
+			if !tt.wantOver && err != nil {
+				t.Errorf("checkBudget = %v, want nil", err)
+			}
+		})
+	}
+}
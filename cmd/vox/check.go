@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cdimoush/vox/recorder"
+)
+
+// errBadAudio is returned when a near-silent or clipped recording is not sent.
+var errBadAudio = errors.New("audio check failed")
+
+// audioProblem describes what is wrong with a recording, or returns ""
+// if its levels look usable.
+func audioProblem(l recorder.Levels) string {
+	switch {
+	case l.Silent():
+		return fmt.Sprintf("Audio is near-silent (peak %.0f dBFS) — is the mic muted?", l.PeakDBFS)
+	case l.Clipping():
+		return fmt.Sprintf("Audio is heavily clipped (%.1f%% of samples) — lower the input gain", 100*l.ClippedFraction())
+	}
+	return ""
+}
+
+// confirmAudio warns about bad audio levels and decides whether to send
+// the audio anyway. ask reads the user's answer; a nil ask (nobody to
+// ask) or skip means bad audio is never sent.
+func confirmAudio(l recorder.Levels, skip bool, ask func() (string, error)) error {
+	problem := audioProblem(l)
+	if problem == "" {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "⚠ %s\n", problem)
+	if skip || ask == nil {
+		return fmt.Errorf("%w: not sent", errBadAudio)
+	}
+
+	fmt.Fprint(os.Stderr, "Send it anyway? [y/N] ")
+	response, err := ask()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(strings.ToLower(response)) != "y" {
+		return fmt.Errorf("%w: not sent", errBadAudio)
+	}
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cdimoush/vox/recorder"
+)
+
+func TestConfirmAudio(t *testing.T) {
+	good := recorder.Levels{PeakDBFS: -8, RMSDBFS: -24, Samples: 16000}
+	silent := recorder.Levels{PeakDBFS: -70, RMSDBFS: -85, Samples: 16000}
+	clipped := recorder.Levels{PeakDBFS: 0, RMSDBFS: -3, Clipped: 800, Samples: 16000}
+
+	answer := func(s string) func() (string, error) {
+		return func() (string, error) { return s + "\n", nil }
+	}
+
+	tests := []struct {
+		name    string
+		levels  recorder.Levels
+		skip    bool
+		ask     func() (string, error)
+		wantErr bool
+	}{
+		{"good audio needs no answer", good, false, nil, false},
+		{"good audio with skip", good, true, nil, false},
+		{"silent, user sends", silent, false, answer("y"), false},
+		{"silent, user declines", silent, false, answer("n"), true},
+		{"silent, default is no", silent, false, answer(""), true},
+		{"clipped, user sends", clipped, false, answer("Y"), false},
+		{"silent, non-interactive", silent, false, nil, true},
+		{"silent, skip flag", silent, true, answer("y"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := confirmAudio(tt.levels, tt.skip, tt.ask)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("confirmAudio: err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errBadAudio) {
+				t.Errorf("expected errBadAudio, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAudioProblem(t *testing.T) {
+	if p := audioProblem(recorder.Levels{PeakDBFS: -10, Samples: 100}); p != "" {
+		t.Errorf("expected no problem, got %q", p)
+	}
+	if p := audioProblem(recorder.Levels{}); p == "" {
+		t.Error("expected empty recording to be reported")
+	}
+}
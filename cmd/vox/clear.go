@@ -5,12 +5,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
-
-	"github.com/cdimoush/vox/history"
 )
 
 func cmdClear() error {
-	store := history.NewStore(history.DefaultPath())
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
 	entries, err := store.List(0)
 	if err != nil {
 		return err
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cdimoush/vox/config"
+)
+
+const configUsage = "Usage: vox config get <key> | set <key> <value> | list"
+
+// managedSettings are changed by commands that also rewrite history, so
+// vox config set refuses them.
+var managedSettings = map[string]string{
+	"history_backend":    "vox history migrate --to jsonl|sqlite",
+	"history_encryption": "vox history encrypt or vox history decrypt",
+	"history_salt":       "vox history encrypt --passphrase",
+}
+
+func cmdConfig() error {
+	args := os.Args[2:]
+	if len(args) == 0 {
+		return fmt.Errorf("%s", configUsage)
+	}
+	switch sub := args[0]; {
+	case sub == "get" && len(args) == 2:
+		return configGet(args[1])
+	case sub == "set" && len(args) == 3:
+		return configSet(args[1], args[2])
+	case sub == "list" && len(args) == 1:
+		return configList(os.Stdout)
+	default:
+		return fmt.Errorf("%s", configUsage)
+	}
+}
+
+// knownSetting returns the setting named key, or an error pointing
+// elsewhere for the API key and unknown keys.
+func knownSetting(key string) (config.Setting, error) {
+	if key == "OPENAI_API_KEY" {
+		return config.Setting{}, fmt.Errorf("the API key isn't a setting\n\nSet it with: vox login")
+	}
+	s, ok := config.Known(key)
+	if !ok {
+		return s, fmt.Errorf("unknown setting %q\n\nSee the settings with: vox config list", key)
+	}
+	return s, nil
+}
+
+// configGet prints the value of key to stdout, and where it came from to
+// stderr.
+func configGet(key string) error {
+	if _, err := knownSetting(key); err != nil {
+		return err
+	}
+	v := config.Lookup(key)
+	fmt.Println(v.Value)
+	fmt.Fprintf(os.Stderr, "(%s)\n", v.Source)
+	return nil
+}
+
+// configSet writes key=value to ~/.vox/config, in the active profile if
+// there is one. An empty value removes the key.
+func configSet(key, value string) error {
+	s, err := knownSetting(key)
+	if err != nil {
+		return err
+	}
+	if how, ok := managedSettings[key]; ok {
+		return fmt.Errorf("%s is set by %s", key, how)
+	}
+	if err := s.Check(value); err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	profile := config.Profile()
+	if err := config.SetIn(profile, key, value); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	where := "~/.vox/config"
+	if profile != "" {
+		where += " [profile " + profile + "]"
+	}
+	if value == "" {
+		fmt.Fprintf(os.Stderr, "✓ Removed %s from %s\n", key, where)
+	} else {
+		fmt.Fprintf(os.Stderr, "✓ Set %s=%s in %s\n", key, value, where)
+	}
+	// A flag, the environment or a project file may still win.
+	if v := config.Lookup(key); value != "" && v.Source != where {
+		fmt.Fprintf(os.Stderr, "⚠ %s overrides it: %s=%s\n", v.Source, key, v.Value)
+	}
+	return nil
+}
+
+// configList prints every setting with its value and source, followed by
+// any per-model prices set in the config files.
+func configList(w io.Writer) error {
+	keys := []string{}
+	for _, s := range config.All() {
+		keys = append(keys, s.Key)
+	}
+	for _, key := range config.FileKeys() {
+		if strings.HasPrefix(key, "price.") {
+			keys = append(keys, key)
+		}
+	}
+
+	fmt.Fprintf(w, "%-22s%-30s%s\n", "Key", "Value", "Source")
+	for _, key := range keys {
+		v := config.Lookup(key)
+		value := v.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%-22s%-30s%s\n", key, value, v.Source)
+	}
+	return nil
+}
@@ -3,37 +3,28 @@ package main
 import (
 	"fmt"
 	"os"
-	"strconv"
 
 	"github.com/cdimoush/vox/clipboard"
-	"github.com/cdimoush/vox/history"
 )
 
 func cmdCp() error {
 	if len(os.Args) < 3 {
-		return fmt.Errorf("Usage: vox cp <n>")
+		return fmt.Errorf("Usage: vox cp <n|id>")
 	}
 
-	n, err := strconv.Atoi(os.Args[2])
+	store, err := openStore()
 	if err != nil {
-		return fmt.Errorf("invalid entry number: %s", os.Args[2])
+		return err
 	}
-
-	store := history.NewStore(history.DefaultPath())
-	entries, err := store.List(0)
+	entry, n, err := findEntry(store, os.Args[2])
 	if err != nil {
 		return err
 	}
 
-	if n < 1 || n > len(entries) {
-		return fmt.Errorf("entry #%d not found (have %d entries)", n, len(entries))
-	}
-
-	entry := entries[n-1]
 	if err := clipboard.Write(entry.Text); err != nil {
 		return err
 	}
 
-	fmt.Fprintf(os.Stderr, "✓ Copied #%d to clipboard\n", n)
+	fmt.Fprintf(os.Stderr, "✓ Copied #%d (%s) to clipboard\n", n, entry.ID)
 	return nil
 }
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cdimoush/vox/clipboard"
+	"github.com/cdimoush/vox/config"
+	"github.com/cdimoush/vox/transcribe"
+)
+
+const doctorUsage = "Usage: vox doctor [--json]"
+
+// apiCheckTimeout bounds the request vox doctor sends to the API.
+const apiCheckTimeout = 10 * time.Second
+
+// Check statuses, from best to worst. skip means the check didn't apply.
+const (
+	statusOK   = "ok"
+	statusSkip = "skip"
+	statusWarn = "warn"
+	statusFail = "fail"
+)
+
+// doctorReport is the vox doctor report. It is also the --json output.
+type doctorReport struct {
+	Version string        `json:"version"`
+	OK      bool          `json:"ok"` // no check failed
+	Checks  []doctorCheck `json:"checks"`
+}
+
+// doctorCheck is one check: config, api_key, sox, recorder, clipboard,
+// history or api.
+type doctorCheck struct {
+	Name    string         `json:"name"`
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Hint    string         `json:"hint,omitempty"` // how to fix it
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// toolInfo is an installed tool and its version, "" if unknown.
+type toolInfo struct {
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// doctorTitles name the checks in the table.
+var doctorTitles = map[string]string{
+	"config":    "Config",
+	"api_key":   "API key",
+	"sox":       "SoX",
+	"recorder":  "Recorder",
+	"clipboard": "Clipboard",
+	"history":   "History",
+	"api":       "API",
+}
+
+// errDoctor is returned when a check failed, after the report is written.
+var errDoctor = errors.New("vox doctor found problems")
+
+func cmdDoctor() error {
+	jsonMode := false
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--json":
+			jsonMode = true
+		default:
+			return fmt.Errorf("unknown argument: %s\n\n%s", arg, doctorUsage)
+		}
+	}
+
+	settings, cfg := doctorConfig()
+	key := doctorKey()
+	r := doctorReport{Version: version, Checks: []doctorCheck{
+		cfg,
+		key,
+		doctorSoX(),
+		doctorRecorder(settings),
+		doctorClipboard(settings),
+		doctorHistory(),
+		doctorAPI(settings, key.Status == statusOK),
+	}}
+	r.OK = true
+	failed := 0
+	for _, c := range r.Checks {
+		if c.Status == statusFail {
+			r.OK = false
+			failed++
+		}
+	}
+
+	if jsonMode {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		if !r.OK {
+			return &jsonError{wrapped: errDoctor}
+		}
+		return nil
+	}
+	writeDoctor(os.Stdout, r)
+	if !r.OK {
+		return fmt.Errorf("%w: %d failed", errDoctor, failed)
+	}
+	return nil
+}
+
+// writeDoctor writes the report as a table, one check per line, with any
+// hint indented below it.
+func writeDoctor(w io.Writer, r doctorReport) {
+	marks := map[string]string{statusOK: "✓", statusSkip: "-", statusWarn: "⚠", statusFail: "✗"}
+	for _, c := range r.Checks {
+		fmt.Fprintf(w, "%s %-10s %s\n", marks[c.Status], doctorTitles[c.Name], c.Message)
+		for _, line := range strings.Split(c.Hint, "\n") {
+			if line != "" {
+				fmt.Fprintf(w, "  %-10s %s\n", "", line)
+			}
+		}
+	}
+}
+
+// doctorConfig loads the settings, reporting the files they come from.
+// Settings that fail to load keep their defaults for the other checks.
+func doctorConfig() (config.Settings, doctorCheck) {
+	c := doctorCheck{Name: "config", Status: statusOK, Details: map[string]any{}}
+	var where []string
+	if p := config.Profile(); p != "" {
+		where = append(where, "profile "+p)
+		c.Details["profile"] = p
+	}
+	p, err := config.FindProject()
+	if p != nil {
+		where = append(where, "project "+p.Path)
+		c.Details["project"] = p.Path
+		if len(p.Ignored) > 0 {
+			c.Status = statusWarn
+			where = append(where, "ignoring "+strings.Join(p.Ignored, ", ")+" in it")
+		}
+	}
+	s, loadErr := config.Load()
+	if err == nil {
+		err = loadErr
+	}
+	if err != nil {
+		c.Status = statusFail
+		c.Message = err.Error()
+		c.Hint = "Fix it with vox config set, or in the file named"
+		return s, c
+	}
+	if len(where) == 0 {
+		where = append(where, "valid")
+	}
+	c.Message = strings.Join(where, "; ")
+	return s, c
+}
+
+// doctorKey reports the key FindAPIKey would use, masked, and where it
+// comes from.
+func doctorKey() doctorCheck {
+	c := doctorCheck{Name: "api_key"}
+	key, source, err := config.LookupAPIKey()
+	switch {
+	case err != nil && source == "credential_command":
+		c.Status, c.Message = statusFail, err.Error()
+		c.Hint = "Fix credential_command, or unset it with: vox config set credential_command \"\""
+	case err != nil:
+		c.Status, c.Message = statusFail, err.Error()
+		c.Hint = "Install or unlock the keyring, or keep the key in ~/.vox/config with: vox config set credential_store file, then vox login"
+	case key == "":
+		c.Status, c.Message = statusFail, "not found"
+		c.Hint = "Run: vox login"
+	default:
+		c.Status = statusOK
+		c.Message = config.MaskKey(key) + " from " + source
+		c.Details = map[string]any{"key": config.MaskKey(key), "source": source}
+	}
+	return c
+}
+
+// soxVersion matches the version sox, rec and soxi print.
+var soxVersion = regexp.MustCompile(`SoX v(\S+)`)
+
+// parseSoXVersion returns the version in SoX's --version output, or "".
+func parseSoXVersion(out string) string {
+	if m := soxVersion.FindStringSubmatch(out); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// doctorSoX reports the SoX tools: sox splits long files, soxi measures
+// durations and rec records.
+func doctorSoX() doctorCheck {
+	c := doctorCheck{Name: "sox", Status: statusOK, Details: map[string]any{}}
+	var found, problems []string
+	for _, tool := range []string{"sox", "soxi", "rec"} {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			c.Details[tool] = toolInfo{}
+			switch tool {
+			case "sox":
+				problems = append(problems, "sox not found: long files can't be split into chunks")
+			case "soxi":
+				problems = append(problems, "soxi not found: durations are unknown, so long files aren't chunked and budgets aren't checked")
+			}
+			continue
+		}
+		out, _ := exec.Command(path, "--version").CombinedOutput()
+		info := toolInfo{Path: path, Version: parseSoXVersion(string(out))}
+		c.Details[tool] = info
+		found = append(found, strings.TrimSpace(tool+" "+info.Version))
+	}
+	if len(problems) > 0 {
+		c.Status = statusWarn
+		c.Message = strings.Join(problems, "; ")
+		c.Hint = "Install SoX: brew install sox, or sudo apt-get install sox"
+		return c
+	}
+	c.Message = strings.Join(found, ", ")
+	return c
+}
+
+// doctorRecorder reports the capture tool vox would record with.
+func doctorRecorder(s config.Settings) doctorCheck {
+	c := doctorCheck{Name: "recorder"}
+	rec, err := newRecorder(s)
+	if err != nil {
+		c.Status = statusFail
+		c.Message, c.Hint, _ = strings.Cut(err.Error(), "\n\n")
+		if c.Hint == "" {
+			c.Hint = "Install it, or change the recorder setting (rec, arecord, parec or sox)"
+		}
+		return c
+	}
+	c.Status, c.Message = statusOK, rec.Name()
+	if s.Recorder != "" {
+		c.Message += " (recorder setting)"
+	}
+	c.Details = map[string]any{"recorder": rec.Name()}
+	return c
+}
+
+// doctorClipboard reports the tool clipboard.Detect picks and, if copying
+// won't work, why.
+func doctorClipboard(s config.Settings) doctorCheck {
+	c := doctorCheck{Name: "clipboard", Details: map[string]any{}}
+	if !s.Clipboard {
+		c.Status, c.Message = statusSkip, "off (clipboard setting)"
+		return c
+	}
+	tool, _ := clipboard.Detect()
+	if tool != "" {
+		c.Details["tool"] = tool
+	}
+	if err := clipboard.Check(); err != nil {
+		c.Status = statusWarn
+		c.Details["reason"] = err.Error()
+		c.Message, c.Hint, _ = strings.Cut(err.Error(), "\n\n")
+		if tool != "" {
+			c.Message = tool + " found, but " + c.Message
+		}
+		c.Message += "; transcripts aren't copied"
+		return c
+	}
+	c.Status, c.Message = statusOK, tool
+	return c
+}
+
+// doctorHistory reads the whole history, reporting unreadable lines and
+// a history file other users can read. It doesn't prompt: passphrase
+// encrypted history is only checked with $VOX_HISTORY_PASSPHRASE set.
+func doctorHistory() doctorCheck {
+	c := doctorCheck{Name: "history", Status: statusOK, Details: map[string]any{}}
+	backend, err := configBackend()
+	if err != nil {
+		c.Status, c.Message = statusFail, err.Error()
+		return c
+	}
+	path := historyPath()
+	if backend == backendSQLite {
+		path = sqlitePath()
+	}
+	mode := config.Get("history_encryption")
+	c.Details["path"], c.Details["backend"] = path, backend
+	if mode != "" && mode != "off" {
+		c.Details["encryption"] = mode
+	}
+	if mode == encryptPassphrase && os.Getenv("VOX_HISTORY_PASSPHRASE") == "" {
+		c.Status = statusSkip
+		c.Message = path + " is encrypted with a passphrase; set VOX_HISTORY_PASSPHRASE to check it"
+		return c
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c.Message = path + ": no history yet"
+		return c
+	}
+	store, err := openStore()
+	if err != nil {
+		c.Status, c.Message = statusFail, err.Error()
+		return c
+	}
+	entries, bad, err := store.ReadAll()
+	if err != nil {
+		c.Status, c.Message = statusFail, path+": "+err.Error()
+		return c
+	}
+	c.Details["entries"] = len(entries)
+	msgs := []string{fmt.Sprintf("%s: %d entries (%s)", path, len(entries), backend)}
+	var hints []string
+	if len(bad) > 0 {
+		c.Status = statusWarn
+		lines := make([]map[string]any, len(bad))
+		for i, b := range bad {
+			lines[i] = map[string]any{"line": b.Line, "error": b.Err.Error()}
+		}
+		c.Details["bad_lines"] = lines
+		msgs = append(msgs, fmt.Sprintf("%d unreadable (first: %v)", len(bad), bad[0]))
+		hints = append(hints, "vox ls and search skip them, and rewrites keep them as they are; fix or delete those lines to clear this")
+	}
+	if info != nil && info.Mode().Perm()&0o077 != 0 {
+		c.Status = statusWarn
+		msgs = append(msgs, fmt.Sprintf("readable by other users (mode %04o)", info.Mode().Perm()))
+		hints = append(hints, "Run: chmod 600 "+path)
+	}
+	c.Message = strings.Join(msgs, "; ")
+	c.Hint = strings.Join(hints, "\n")
+	return c
+}
+
+// doctorAPI checks that the endpoint answers and accepts the key.
+func doctorAPI(s config.Settings, haveKey bool) doctorCheck {
+	c := doctorCheck{Name: "api", Details: map[string]any{"endpoint": s.BaseURL}}
+	if !haveKey {
+		c.Status, c.Message = statusSkip, "no API key to check "+s.BaseURL+" with"
+		return c
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiCheckTimeout)
+	defer cancel()
+	reached, err := transcribe.CheckAPI(ctx)
+	c.Details["reachable"] = reached
+	switch {
+	case err == nil:
+		c.Status, c.Message = statusOK, s.BaseURL+" reachable, key accepted"
+	case reached:
+		c.Status, c.Message = statusFail, s.BaseURL+" answered: "+err.Error()
+		c.Hint = "Check the key (vox login) and the model your account can use"
+	default:
+		c.Status, c.Message = statusFail, "can't reach "+s.BaseURL+": "+err.Error()
+		c.Hint = "Check your network or proxy, or the base_url setting"
+	}
+	return c
+}
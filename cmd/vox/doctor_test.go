@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSoXVersion(t *testing.T) {
+	tests := map[string]string{
+		"sox:      SoX v14.4.2\n":        "14.4.2",
+		"rec:      SoX v14.4.2-debian\n": "14.4.2-debian",
+		"soxi: invalid option -- '-'\n":  "",
+		"":                               "",
+	}
+	for out, want := range tests {
+		if got := parseSoXVersion(out); got != want {
+			t.Errorf("parseSoXVersion(%q) = %q, want %q", out, got, want)
+		}
+	}
+}
+
+func TestWriteDoctor(t *testing.T) {
+	r := doctorReport{Checks: []doctorCheck{
+		{Name: "api_key", Status: statusOK, Message: "sk-…wxyz from ~/.bashrc"},
+		{Name: "recorder", Status: statusFail, Message: "rec not found on PATH", Hint: "Install it\nor not"},
+		{Name: "clipboard", Status: statusSkip, Message: "off (clipboard setting)"},
+	}}
+	var buf bytes.Buffer
+	writeDoctor(&buf, r)
+	want := "✓ API key    sk-…wxyz from ~/.bashrc\n" +
+		"✗ Recorder   rec not found on PATH\n" +
+		"             Install it\n" +
+		"             or not\n" +
+		"- Clipboard  off (clipboard setting)\n"
+	if buf.String() != want {
+		t.Errorf("writeDoctor =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDoctorKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("VOX_PROFILE", "")
+
+	t.Setenv("OPENAI_API_KEY", "")
+	if c := doctorKey(); c.Status != statusFail || c.Hint != "Run: vox login" {
+		t.Errorf("no key: %+v", c)
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-abcdefghijklmnopWXYZ")
+	c := doctorKey()
+	if c.Status != statusOK || c.Message != "sk-…WXYZ from env OPENAI_API_KEY" {
+		t.Errorf("env key: %+v", c)
+	}
+	if strings.Contains(c.Message, "abcdefgh") || c.Details["key"] != "sk-…WXYZ" {
+		t.Errorf("env key not masked: %+v", c)
+	}
+}
+
+func TestDoctorHistory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("VOX_PROFILE", "")
+	t.Setenv("VOX_HISTORY_PATH", "")
+	path := filepath.Join(home, ".vox", "history.jsonl")
+
+	if c := doctorHistory(); c.Status != statusOK || !strings.HasSuffix(c.Message, "no history yet") {
+		t.Errorf("no history: %+v", c)
+	}
+
+	os.MkdirAll(filepath.Dir(path), 0700)
+	good := `{"ts":"2026-03-01T10:00:00Z","text":"hello","duration_s":1}` + "\n"
+	os.WriteFile(path, []byte(good), 0600)
+	if c := doctorHistory(); c.Status != statusOK || c.Details["entries"] != 1 {
+		t.Errorf("healthy history: %+v", c)
+	}
+
+	os.WriteFile(path, []byte(good+"{\"ts\":\n"+good), 0644)
+	os.Chmod(path, 0644)
+	c := doctorHistory()
+	if c.Status != statusWarn {
+		t.Fatalf("damaged history: %+v", c)
+	}
+	bad, _ := c.Details["bad_lines"].([]map[string]any)
+	if len(bad) != 1 || bad[0]["line"] != 2 {
+		t.Errorf("bad_lines = %v, want line 2", c.Details["bad_lines"])
+	}
+	if !strings.Contains(c.Message, "mode 0644") || !strings.Contains(c.Hint, "chmod 600 "+path) {
+		t.Errorf("world-readable history not reported: %+v", c)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func cmdEdit() error {
+	if len(os.Args) != 3 {
+		return fmt.Errorf("Usage: vox edit <n|id>")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	entry, n, err := findEntry(store, os.Args[2])
+	if err != nil {
+		return err
+	}
+
+	// The editor needs the text in a plain file, however history is kept.
+	if s, ok := store.(*history.Store); ok && s.Cipher() != nil {
+		fmt.Fprintln(os.Stderr, "⚠ History is encrypted, but the editor gets the text in a plaintext temp file (readable only by you, removed when it exits)")
+	}
+	text, err := editText(entry.Text)
+	if err != nil {
+		return err
+	}
+	if text == entry.Text {
+		fmt.Fprintln(os.Stderr, "No changes.")
+		return nil
+	}
+	if text == "" {
+		return fmt.Errorf("edited text is empty; use vox rm %s to delete the entry", entry.ID)
+	}
+
+	updated, err := store.Update(entry.ID, func(e *history.Entry) { e.Text = text })
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Updated #%d (%s)%s\n", n, entry.ID, sharedSuffix(updated))
+	return nil
+}
+
+// editText opens text in $VISUAL or $EDITOR (default vi) and returns the
+// edited result with surrounding whitespace trimmed.
+func editText(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The transcript may be private: the file is only readable by the
+	// user, and removed on every path out, Ctrl+C included.
+	tmp, err := os.CreateTemp("", "vox-edit-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	err = tmp.Chmod(0o600)
+	if err == nil {
+		_, err = tmp.WriteString(text + "\n")
+	}
+	tmp.Close()
+	if err != nil {
+		return "", fmt.Errorf("writing temp file: %w", err)
+	}
+
+	// Ctrl+C is the editor's to handle; vox must live to remove the file.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	// Run through the shell so EDITOR may carry arguments, e.g. "code --wait".
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", tmp.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor %q: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", fmt.Errorf("reading edited text: %w", err)
+	}
+	return strings.TrimSpace(string(edited)), nil
+}
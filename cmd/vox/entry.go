@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cdimoush/vox/history"
+)
+
+// findEntry looks up a history entry by position (as shown by vox ls) or
+// by ID. It returns the entry and its 1-based position.
+func findEntry(store history.Backend, ref string) (history.Entry, int, error) {
+	entries, err := store.List(0)
+	if err != nil {
+		return history.Entry{}, 0, err
+	}
+	i, err := history.Resolve(entries, ref)
+	if err != nil {
+		return history.Entry{}, 0, err
+	}
+	return entries[i], i + 1, nil
+}
+
+// sharedSuffix describes the other entries a command acted on because
+// they share the entry's ID (the same timestamp and text), given how many
+// entries have it. It is "" when the ID is unique.
+func sharedSuffix(n int) string {
+	switch {
+	case n <= 1:
+		return ""
+	case n == 2:
+		return " and 1 duplicate with the same ID"
+	}
+	return fmt.Sprintf(" and %d duplicates with the same ID", n-1)
+}
+
+// warnSkipped tells the user, on stderr, about history lines the last
+// List or Search skipped because they couldn't be read.
+func warnSkipped(store history.Backend) {
+	warnBadLines(store.Skipped())
+}
+
+// warnBadLines tells the user, on stderr, about unreadable history lines
+// left out of what they asked for.
+func warnBadLines(bad []history.BadLine) {
+	switch len(bad) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(os.Stderr, "⚠ Skipped 1 unreadable history line (%v); run vox doctor for details\n", bad[0])
+	default:
+		fmt.Fprintf(os.Stderr, "⚠ Skipped %d unreadable history lines (first: %v); run vox doctor for details\n", len(bad), bad[0])
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cdimoush/vox/history"
+)
+
+const exportUsage = "Usage: vox export [--format md|csv|json] [--since 7d] [--until 2026-03-01] [-o file]"
+
+// exporters write entries (oldest first) in each supported format.
+var exporters = map[string]func(io.Writer, []history.Entry) error{
+	"md":   exportMarkdown,
+	"csv":  exportCSV,
+	"json": exportJSON,
+}
+
+func cmdExport() error {
+	var (
+		q       history.Query
+		format  = "md"
+		outPath string
+		now     = time.Now()
+	)
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format", "--since", "--until", "-o":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a value\n\n%s", args[i], exportUsage)
+			}
+			flag, val := args[i], args[i+1]
+			i++
+			var err error
+			switch flag {
+			case "--format":
+				format = val
+			case "--since":
+				q.Since, err = parseTimeBound(val, now, false)
+			case "--until":
+				q.Until, err = parseTimeBound(val, now, true)
+			case "-o":
+				outPath = val
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %v\n\n%s", flag, err, exportUsage)
+			}
+		default:
+			return fmt.Errorf("unknown argument: %s\n\n%s", args[i], exportUsage)
+		}
+	}
+	export, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q\n\n%s", format, exportUsage)
+	}
+
+	matcher, err := q.Compile()
+	if err != nil {
+		return err
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	all, bad, err := store.ReadAll()
+	if err != nil {
+		return err
+	}
+	// Don't let an export be quietly incomplete.
+	warnBadLines(bad)
+	var entries []history.Entry
+	for _, e := range all {
+		if _, ok := matcher.Match(e); ok {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No entries to export.")
+		return nil
+	}
+
+	if outPath == "" {
+		return export(os.Stdout, entries)
+	}
+	// Transcripts can be sensitive: keep the file private like the history.
+	f, err := os.OpenFile(outPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := export(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ Exported %d entries to %s\n", len(entries), outPath)
+	return nil
+}
+
+// exportMarkdown writes one section per local calendar day, with each
+// entry under its local time of day.
+func exportMarkdown(w io.Writer, entries []history.Entry) error {
+	lastDay := ""
+	for _, e := range entries {
+		heading, when := e.Timestamp, ""
+		if t, err := e.Time(); err == nil {
+			t = t.Local()
+			heading, when = t.Format("Monday, January 2, 2006"), t.Format("15:04")
+		}
+		if heading != lastDay {
+			if _, err := fmt.Fprintf(w, "## %s\n\n", heading); err != nil {
+				return err
+			}
+			lastDay = heading
+		}
+		if when != "" {
+			if _, err := fmt.Fprintf(w, "### %s\n\n", when); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n\n", e.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportCSV writes a header row and one row per entry. encoding/csv
+// quotes fields containing commas, quotes or newlines.
+func exportCSV(w io.Writer, entries []history.Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "timestamp", "local_time", "duration_s", "text"})
+	for _, e := range entries {
+		local := ""
+		if t, err := e.Time(); err == nil {
+			local = t.Local().Format("2006-01-02 15:04:05")
+		}
+		cw.Write([]string{e.ID, e.Timestamp, local, strconv.FormatFloat(e.DurationS, 'f', -1, 64), e.Text})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportJSON writes the entries as an indented JSON array of history lines.
+func exportJSON(w io.Writer, entries []history.Entry) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func exportFixture() []history.Entry {
+	ts := func(day, hour int) string {
+		return time.Date(2026, 3, day, hour, 5, 0, 0, time.Local).UTC().Format(time.RFC3339)
+	}
+	return []history.Entry{
+		{Timestamp: ts(2, 9), Text: "First thought", DurationS: 3.5, ID: "aaaa0001"},
+		{Timestamp: ts(2, 14), Text: "Second, with \"quotes\"\nand a second line", DurationS: 12, ID: "aaaa0002"},
+		{Timestamp: ts(3, 8), Text: "Next day", DurationS: 1, ID: "aaaa0003"},
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := exportMarkdown(&b, exportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	want := "## Monday, March 2, 2026\n\n" +
+		"### 09:05\n\nFirst thought\n\n" +
+		"### 14:05\n\nSecond, with \"quotes\"\nand a second line\n\n" +
+		"## Tuesday, March 3, 2026\n\n" +
+		"### 08:05\n\nNext day\n\n"
+	if b.String() != want {
+		t.Errorf("markdown:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	var b strings.Builder
+	if err := exportCSV(&b, exportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want header + 3", len(rows))
+	}
+	if rows[0][4] != "text" || rows[2][0] != "aaaa0002" || rows[2][3] != "12" {
+		t.Errorf("unexpected rows: %q", rows)
+	}
+	if rows[2][4] != "Second, with \"quotes\"\nand a second line" {
+		t.Errorf("multi-line text did not round-trip: %q", rows[2][4])
+	}
+	if rows[1][2] != "2026-03-02 09:05:00" {
+		t.Errorf("local_time: got %q", rows[1][2])
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	var b strings.Builder
+	if err := exportJSON(&b, exportFixture()); err != nil {
+		t.Fatal(err)
+	}
+	var got []history.Entry
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("output is not a JSON array of entries: %v", err)
+	}
+	if !reflect.DeepEqual(got, exportFixture()) {
+		t.Errorf("entries did not round-trip: %+v", got)
+	}
+}
@@ -1,19 +1,24 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/term"
+
 	"github.com/cdimoush/vox/clipboard"
 	"github.com/cdimoush/vox/config"
 	"github.com/cdimoush/vox/history"
+	"github.com/cdimoush/vox/recorder"
 	"github.com/cdimoush/vox/transcribe"
 )
 
@@ -52,14 +57,19 @@ func parseFileFlags() (bool, string) {
 
 func cmdFile() error {
 	if len(os.Args) < 3 {
-		return fmt.Errorf("Usage: vox file <path> [--json] [--format=ogg]")
+		return fmt.Errorf("Usage: vox file <path> [--json] [--format=ogg] [--check-audio [--skip-bad-audio]] [--yes] [--tag name]...")
 	}
 
 	filePath := os.Args[2]
 	jsonMode, format := parseFileFlags()
+	tags, err := parseFileTags()
+	if err != nil {
+		return wrapErr(jsonMode, err)
+	}
+	fromStdin := filePath == "-"
 
 	// Stdin mode: read all of stdin into a temp file.
-	if filePath == "-" {
+	if fromStdin {
 		tmp, err := os.CreateTemp("", "vox-stdin-*."+format)
 		if err != nil {
 			return wrapErr(jsonMode, fmt.Errorf("creating temp file: %w", err))
@@ -78,9 +88,42 @@ func cmdFile() error {
 		}
 	}
 
-	if config.FindAPIKey() == "" {
+	key, _, err := config.LookupAPIKey()
+	if err != nil {
+		return wrapErr(jsonMode, err)
+	}
+	if key == "" {
 		return wrapErr(jsonMode, transcribe.ErrNoAPIKey)
 	}
+	settings, err := config.Load()
+	if err != nil {
+		return wrapErr(jsonMode, err)
+	}
+	if !jsonMode {
+		warnProject()
+	}
+	// Open history before uploading, so a bad config or a passphrase
+	// prompt comes first.
+	store, err := openStore()
+	if err != nil {
+		return wrapErr(jsonMode, err)
+	}
+
+	if hasFlag(os.Args[3:], "--check-audio") {
+		if err := checkFileAudio(filePath, jsonMode, fromStdin); err != nil {
+			return wrapErr(jsonMode, err)
+		}
+	}
+
+	var ask func() (string, error)
+	if !jsonMode && !fromStdin && term.IsTerminal(int(os.Stdin.Fd())) {
+		ask = func() (string, error) {
+			return bufio.NewReader(os.Stdin).ReadString('\n')
+		}
+	}
+	if err := checkBudget(filePath, hasFlag(os.Args[3:], "--yes"), jsonMode, ask); err != nil {
+		return wrapErr(jsonMode, err)
+	}
 
 	// Ctrl+C aborts transcription.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -120,7 +163,7 @@ func cmdFile() error {
 		}()
 	}
 
-	text, duration, err := transcribeWithContext(ctx, filePath)
+	tr, err := transcribeWithContext(ctx, filePath)
 	close(spinnerDone)
 	spinnerWg.Wait()
 
@@ -128,19 +171,15 @@ func cmdFile() error {
 		return wrapErr(jsonMode, err)
 	}
 
-	trimmed := strings.TrimSpace(text)
-
-	// Determine chunk count from duration.
-	chunks := 1
-	if duration > transcribe.ChunkThreshold {
-		chunks = int(duration/transcribe.ChunkDuration) + 1
-	}
+	trimmed := strings.TrimSpace(tr.Text)
+	entry := newEntry(trimmed, tr.Duration, tr)
+	recordSpend(entry, jsonMode)
 
 	if jsonMode {
 		result := fileResult{
 			Text:     trimmed,
-			Duration: duration,
-			Chunks:   chunks,
+			Duration: tr.Duration,
+			Chunks:   tr.Chunks,
 		}
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetEscapeHTML(false)
@@ -152,7 +191,7 @@ func cmdFile() error {
 	fmt.Fprintf(os.Stderr, "\n\"%s\"\n\n", trimmed)
 
 	// Copy to clipboard (best-effort).
-	if clipboard.Available() {
+	if settings.Clipboard && clipboard.Available() {
 		if err := clipboard.Write(trimmed); err != nil {
 			fmt.Fprintf(os.Stderr, "⚠ Clipboard unavailable: %v\n", err)
 		} else {
@@ -160,12 +199,14 @@ func cmdFile() error {
 		}
 	}
 
-	store := history.NewStore(history.DefaultPath())
-	entry := history.Entry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Text:      trimmed,
-		DurationS: duration,
+	entry.Source = history.SourceStdin
+	if !fromStdin {
+		entry.Source = history.SourceFile
+		if abs, err := filepath.Abs(filePath); err == nil {
+			entry.File = abs
+		}
 	}
+	entry.Tags = tags
 	if err := store.Append(entry); err != nil {
 		return fmt.Errorf("saving history: %w", err)
 	}
@@ -173,6 +214,60 @@ func cmdFile() error {
 	return nil
 }
 
+// parseFileTags collects --tag flags from os.Args (after the path).
+func parseFileTags() ([]string, error) {
+	var tags []string
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		tag, skip, ok, err := parseTagFlag(args, i)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			tags = addTags(tags, tag)
+			i += skip
+		}
+	}
+	return tags, nil
+}
+
+// hasFlag reports whether flag appears in args.
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFileAudio runs the silence/clipping check for --check-audio.
+// The user is only asked to confirm when stdin is a terminal that isn't
+// supplying the audio and stderr chrome is enabled.
+func checkFileAudio(path string, jsonMode, fromStdin bool) error {
+	levels, err := recorder.Analyze(path)
+	if err != nil {
+		if !jsonMode {
+			fmt.Fprintf(os.Stderr, "⚠ Could not check audio: %v\n", err)
+		}
+		return nil
+	}
+	if jsonMode {
+		// Keep stderr silent; the problem is reported in the JSON error.
+		if problem := audioProblem(levels); problem != "" {
+			return fmt.Errorf("%w: %s", errBadAudio, problem)
+		}
+		return nil
+	}
+	var ask func() (string, error)
+	if !fromStdin && isTerminal(os.Stdin) {
+		ask = func() (string, error) {
+			return bufio.NewReader(os.Stdin).ReadString('\n')
+		}
+	}
+	return confirmAudio(levels, hasFlag(os.Args[3:], "--skip-bad-audio"), ask)
+}
+
 // wrapErr handles errors in JSON vs normal mode.
 // In JSON mode: writes error JSON to stdout and returns a jsonError
 // (so main.go knows to skip stderr output but still exit with correct code).
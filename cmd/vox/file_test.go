@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/cdimoush/vox/budget"
 	"github.com/cdimoush/vox/transcribe"
 )
 
@@ -41,6 +42,7 @@ func TestCmdFileNonexistentFile(t *testing.T) {
 func TestCmdFileMissingAPIKey(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "")
 	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	tmpFile, err := os.CreateTemp("", "vox-test-*.wav")
 	if err != nil {
@@ -65,6 +67,7 @@ func TestCmdFileMissingAPIKey(t *testing.T) {
 func TestCmdFileMissingAPIKeyJSON(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "")
 	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	tmpFile, err := os.CreateTemp("", "vox-test-*.wav")
 	if err != nil {
@@ -103,6 +106,8 @@ func TestExitCodeMapping(t *testing.T) {
 		{"wrapped api error", fmt.Errorf("transcription: %w", transcribe.ErrAPI), 2},
 		{"json wrapped no api key", &jsonError{wrapped: transcribe.ErrNoAPIKey}, 3},
 		{"json wrapped api error", &jsonError{wrapped: fmt.Errorf("fail: %w", transcribe.ErrAPI)}, 2},
+		{"over budget", fmt.Errorf("%w: not sent", budget.ErrExceeded), 4},
+		{"json wrapped over budget", &jsonError{wrapped: budget.ErrExceeded}, 4},
 		{"general error", errors.New("file not found"), 1},
 		{"json wrapped general", &jsonError{wrapped: errors.New("bad format")}, 1},
 	}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/cdimoush/vox/config"
 )
 
 // relativeTime converts an RFC3339 timestamp to a human-friendly relative string.
@@ -36,3 +39,48 @@ func truncate(s string, max int) string {
 	}
 	return s[:max-3] + "..."
 }
+
+// formatDuration renders seconds compactly: "42s", "12m04s", "1h05m".
+func formatDuration(seconds float64) string {
+	s := int(seconds + 0.5)
+	switch {
+	case s < 60:
+		return fmt.Sprintf("%ds", s)
+	case s < 3600:
+		return fmt.Sprintf("%dm%02ds", s/60, s%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", s/3600, s%3600/60)
+	}
+}
+
+// parseTimeBound parses a --since/--until value: a relative age such as
+// "30m", "12h", "7d" or "2w" (counted back from now), a local date
+// "2006-01-02", or an RFC3339 timestamp. If endOfDay is set, a bare date
+// means the end of that day, so that "--until 2026-03-01" includes it.
+func parseTimeBound(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	if age, err := config.ParseAge(s); err == nil {
+		return now.Add(-age), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want e.g. 7d, 12h, 2026-03-01 or an RFC3339 timestamp)", s)
+}
+
+// parseSeconds parses a duration given as plain seconds ("90") or a Go
+// duration ("1m30s") and returns it in seconds.
+func parseSeconds(s string) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil && v >= 0 {
+		return v, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d.Seconds(), nil
+	}
+	return 0, fmt.Errorf("invalid duration %q (want seconds or e.g. 1m30s)", s)
+}
@@ -63,3 +63,72 @@ func TestRelativeTime(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+	tests := []struct {
+		in       string
+		endOfDay bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{"7d", false, now.AddDate(0, 0, -7), false},
+		{"12h", false, now.Add(-12 * time.Hour), false},
+		{"30m", false, now.Add(-30 * time.Minute), false},
+		{"2w", false, now.AddDate(0, 0, -14), false},
+		{"2026-03-01", false, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), false},
+		{"2026-03-01", true, time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), false},
+		{"2026-03-01T08:00:00Z", true, time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), false},
+		{"yesterday", false, time.Time{}, true},
+		{"7y", false, time.Time{}, true},
+		{"-3d", false, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.in, now, tt.endOfDay)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeBound(%q): err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q, %v) = %v, want %v", tt.in, tt.endOfDay, got, tt.want)
+		}
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"30", 30, false},
+		{"2.5", 2.5, false},
+		{"1m30s", 90, false},
+		{"-5", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSeconds(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSeconds(%q) = %v, %v; want %v (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0s"},
+		{42.4, "42s"},
+		{59.6, "1m00s"},
+		{724, "12m04s"},
+		{3900, "1h05m"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.in); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/cdimoush/vox/config"
+	"github.com/cdimoush/vox/history"
+)
+
+const historyUsage = "Usage: vox history encrypt [--passphrase] | vox history decrypt | vox history migrate --to jsonl|sqlite [--force]"
+
+func cmdHistory() error {
+	if len(os.Args) < 3 {
+		return fmt.Errorf("%s", historyUsage)
+	}
+	switch os.Args[2] {
+	case "encrypt":
+		return historyEncrypt(os.Args[3:])
+	case "decrypt":
+		if len(os.Args) != 3 {
+			return fmt.Errorf("%s", historyUsage)
+		}
+		return historyDecrypt()
+	case "migrate":
+		return historyMigrate(os.Args[3:])
+	default:
+		return fmt.Errorf("unknown history command: %s\n\n%s", os.Args[2], historyUsage)
+	}
+}
+
+// historyEncrypt seals the whole history and records the mode in
+// ~/.vox/config so later commands encrypt and decrypt transparently.
+func historyEncrypt(args []string) error {
+	mode := encryptKeyFile
+	for _, arg := range args {
+		if arg != "--passphrase" {
+			return fmt.Errorf("unknown flag: %s\n\n%s", arg, historyUsage)
+		}
+		mode = encryptPassphrase
+	}
+
+	if err := requireJSONL("encryption"); err != nil {
+		return err
+	}
+	current := config.Get("history_encryption")
+	if current != "" && current != "off" && current != mode {
+		return fmt.Errorf("history is already encrypted with a %s\n\nRun vox history decrypt first", modeName(current))
+	}
+	cur, err := configCipher()
+	if err != nil {
+		return err
+	}
+	store := history.NewStore(historyPath())
+	store.SetCipher(cur)
+
+	// Already in this mode: rewrite with the same cipher, which seals any
+	// plaintext lines an older vox appended.
+	c := cur
+	var salt []byte
+	newKey := false
+	switch {
+	case c != nil:
+	case mode == encryptKeyFile:
+		key, err := readKeyFile()
+		if errors.Is(err, os.ErrNotExist) {
+			if key, err = history.RandomBytes(history.KeySize); err != nil {
+				return err
+			}
+			newKey = true
+		} else if err != nil {
+			return err
+		}
+		if c, err = history.NewKeyCipher(key); err != nil {
+			return err
+		}
+		if newKey {
+			if err := writeKeyFile(key); err != nil {
+				return err
+			}
+		}
+	case mode == encryptPassphrase:
+		if salt, err = history.RandomBytes(16); err != nil {
+			return err
+		}
+		pass, err := historyPassphrase(true)
+		if err != nil {
+			return err
+		}
+		if c, err = history.NewPassphraseCipher(pass, salt); err != nil {
+			return err
+		}
+	}
+
+	// Save the mode and salt before sealing anything: encrypted lines with
+	// no mode in the config can't be read. If sealing fails the history
+	// is unchanged, so the old settings are put back.
+	prevSalt := config.Get("history_salt")
+	if salt != nil {
+		if err := config.Set("history_salt", base64.RawStdEncoding.EncodeToString(salt)); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+	}
+	if err := config.Set("history_encryption", mode); err != nil {
+		config.Set("history_salt", prevSalt)
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := store.Encrypt(c); err != nil {
+		config.Set("history_encryption", current)
+		config.Set("history_salt", prevSalt)
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ History encrypted with a %s\n", modeName(mode))
+	if newKey {
+		fmt.Fprintf(os.Stderr, "⚠ Back up %s: without it your history can't be read\n", keyFilePath())
+	}
+	return nil
+}
+
+// historyDecrypt rewrites the history as plaintext and turns encryption
+// off. The key file is left in place for old backups.
+func historyDecrypt() error {
+	if err := requireJSONL("encryption"); err != nil {
+		return err
+	}
+	c, err := configCipher()
+	if err != nil {
+		return err
+	}
+	store := history.NewStore(historyPath())
+	store.SetCipher(c)
+	if err := store.Decrypt(); err != nil {
+		return err
+	}
+	if err := config.Set("history_encryption", ""); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := config.Set("history_salt", ""); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "✓ History decrypted")
+	return nil
+}
+
+// historyMigrate copies every entry to the other backend, checks the copy
+// reads back identically, and switches history_backend over. The old
+// store is left in place.
+func historyMigrate(args []string) error {
+	to, force := "", false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--to":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--to requires jsonl or sqlite\n\n%s", historyUsage)
+			}
+			i++
+			to = args[i]
+		case "--force":
+			force = true
+		default:
+			return fmt.Errorf("unknown flag: %s\n\n%s", args[i], historyUsage)
+		}
+	}
+	var dst history.Backend
+	var dstPath, srcPath string
+	switch to {
+	case backendSQLite:
+		dst, dstPath, srcPath = history.NewSQLite(sqlitePath()), sqlitePath(), historyPath()
+	case backendJSONL:
+		dst, dstPath, srcPath = history.NewStore(historyPath()), historyPath(), sqlitePath()
+	default:
+		return fmt.Errorf("%s", historyUsage)
+	}
+
+	from, err := configBackend()
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return fmt.Errorf("history already uses the %s backend", to)
+	}
+	if mode := config.Get("history_encryption"); to == backendSQLite && mode != "" && mode != "off" {
+		return fmt.Errorf("history_encryption isn't supported with the sqlite backend\n\nRun vox history decrypt first")
+	}
+
+	src, err := openStore()
+	if err != nil {
+		return err
+	}
+	entries, bad, err := src.ReadAll()
+	if err != nil {
+		return err
+	}
+	// A migration is lossless or it doesn't happen.
+	if len(bad) > 0 {
+		return fmt.Errorf("%s has %d unreadable entries (first: %v); history_backend is unchanged\n\nFix or delete them (vox doctor lists them), then migrate again", srcPath, len(bad), bad[0])
+	}
+
+	if _, err := os.Stat(dstPath); err == nil {
+		existing, err := dst.List(1)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 && !force {
+			return fmt.Errorf("%s already has entries\n\nPass --force to replace them", dstPath)
+		}
+	}
+	if err := migrateTo(to, dstPath, entries); err != nil {
+		return err
+	}
+	if err := config.Set("history_backend", to); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Migrated %d entries to %s\n", len(entries), dstPath)
+	fmt.Fprintf(os.Stderr, "The old history is still at %s; delete it once you're happy.\n", srcPath)
+	return nil
+}
+
+// migrateTo writes entries to a new history of kind to beside dstPath,
+// checks it reads back identically, and only then renames it over
+// dstPath, so a failed migration never touches an existing target.
+func migrateTo(to, dstPath string, entries []history.Entry) error {
+	f, err := os.CreateTemp(filepath.Dir(dstPath), ".history-migrate-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+	defer func() {
+		for _, p := range []string{tmp, tmp + ".lock", tmp + "-wal", tmp + "-shm"} {
+			os.Remove(p)
+		}
+	}()
+
+	var dst history.Backend = history.NewStore(tmp)
+	if to == backendSQLite {
+		dst = history.NewSQLite(tmp)
+	}
+	if err := dst.Rewrite(func([]history.Entry) ([]history.Entry, error) { return entries, nil }); err != nil {
+		return err
+	}
+	copied, bad, err := dst.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(bad) > 0 || !reflect.DeepEqual(copied, entries) {
+		return fmt.Errorf("migrated history doesn't match the original; history_backend is unchanged")
+	}
+
+	if to == backendSQLite {
+		// A stale journal of the database being replaced would be
+		// applied to the new one.
+		os.Remove(dstPath + "-wal")
+		os.Remove(dstPath + "-shm")
+	}
+	return os.Rename(tmp, dstPath)
+}
+
+// requireJSONL fails if history isn't on the jsonl backend, which is the
+// only one that supports feature.
+func requireJSONL(feature string) error {
+	kind, err := configBackend()
+	if err != nil {
+		return err
+	}
+	if kind != backendJSONL {
+		return fmt.Errorf("history %s needs the jsonl backend\n\nRun vox history migrate --to jsonl first", feature)
+	}
+	return nil
+}
+
+// writeKeyFile saves a new history key, refusing to replace an existing one.
+func writeKeyFile(key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(keyFilePath()), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(keyFilePath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("saving history key: %w", err)
+	}
+	_, err = fmt.Fprintln(f, base64.StdEncoding.EncodeToString(key))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func modeName(mode string) string {
+	if mode == encryptPassphrase {
+		return "passphrase"
+	}
+	return "key file"
+}
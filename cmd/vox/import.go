@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func cmdImport() error {
+	if len(os.Args) != 3 {
+		return fmt.Errorf("Usage: vox import <history.jsonl>")
+	}
+	path := os.Args[2]
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("file not found: %s", path)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	// An encrypted export from another machine opens with the same key
+	// or passphrase.
+	src := history.NewStore(path)
+	if s, ok := store.(*history.Store); ok {
+		src.SetCipher(s.Cipher())
+	}
+	incoming, bad, err := src.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(bad) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠ Skipped %d malformed lines in %s (first: %v)\n", len(bad), path, bad[0])
+	}
+
+	added, skipped, err := store.Merge(incoming)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ Imported %d entries (%d duplicates skipped)\n", added, skipped)
+	return nil
+}
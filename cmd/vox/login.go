@@ -4,17 +4,34 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
+	"golang.org/x/term"
+
 	"github.com/cdimoush/vox/config"
 )
 
+const loginUsage = "Usage: vox login [--profile name]"
+
 func cmdLogin() error {
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		skip, ok, err := parseGlobalFlag(args, i)
+		if !ok {
+			return fmt.Errorf("unknown argument: %s\n\n%s", args[i], loginUsage)
+		}
+		if err != nil {
+			return fmt.Errorf("%v\n\n%s", err, loginUsage)
+		}
+		i += skip
+	}
+	if err := checkProfile(); err != nil {
+		return err
+	}
+
 	fmt.Fprint(os.Stderr, "Enter your OpenAI API key: ")
 
-	reader := bufio.NewReader(os.Stdin)
-	key, err := reader.ReadString('\n')
+	key, err := readKey()
 	if err != nil {
 		return fmt.Errorf("reading input: %w", err)
 	}
@@ -27,43 +44,37 @@ func cmdLogin() error {
 		return fmt.Errorf("invalid key: expected it to start with sk-")
 	}
 
-	// Save to ~/.vox/config.
-	if err := config.SaveAPIKey(key); err != nil {
+	saved, err := config.SaveAPIKey(key)
+	if err != nil {
 		return fmt.Errorf("saving key: %w", err)
 	}
+	if saved.Fallback != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Saving the key in plaintext: %v\n", saved.Fallback)
+	}
+	p := config.Profile()
+	if p == "" {
+		fmt.Fprintf(os.Stderr, "✓ Key %s saved to %s\n", config.MaskKey(key), saved.Where)
+	} else {
+		fmt.Fprintf(os.Stderr, "✓ Key %s saved to profile %s in %s\n", config.MaskKey(key), p, saved.Where)
+		fmt.Fprintf(os.Stderr, "\nUse it with: vox --profile %s, or export VOX_PROFILE=%s\n", p, p)
+	}
 
-	// Also suggest writing to the shell profile so it's available system-wide.
-	profile := detectShellProfile()
-	fmt.Fprintf(os.Stderr, "✓ Key saved to ~/.vox/config\n")
-	if profile != "" {
-		short := "~/" + filepath.Base(filepath.Dir(profile)) + "/" + filepath.Base(profile)
-		if filepath.Dir(profile) == os.Getenv("HOME") {
-			short = "~/" + filepath.Base(profile)
-		}
-		fmt.Fprintf(os.Stderr, "\nTo make it permanent in new shells, add to %s:\n", short)
-		fmt.Fprintf(os.Stderr, "  export OPENAI_API_KEY=%s\n", key)
-		fmt.Fprintf(os.Stderr, "Then run: source %s\n", short)
+	if os.Getenv("OPENAI_API_KEY") != "" {
+		fmt.Fprintln(os.Stderr, "⚠ OPENAI_API_KEY is set in your environment and takes precedence over the saved key")
+	} else if config.Get("credential_command") != "" {
+		fmt.Fprintln(os.Stderr, "⚠ credential_command is set and takes precedence over the saved key")
 	}
 	return nil
 }
 
-// detectShellProfile returns the most appropriate shell profile file to suggest.
-// Preference: ~/.zshrc > ~/.bashrc > ~/.bash_profile > ~/.profile
-func detectShellProfile() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-	candidates := []string{
-		filepath.Join(home, ".zshrc"),
-		filepath.Join(home, ".bashrc"),
-		filepath.Join(home, ".bash_profile"),
-		filepath.Join(home, ".profile"),
-	}
-	for _, p := range candidates {
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
+// readKey reads the key from stdin, without echoing it if stdin is a
+// terminal.
+func readKey() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		key, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(key), err
 	}
-	return ""
+	return bufio.NewReader(os.Stdin).ReadString('\n')
 }
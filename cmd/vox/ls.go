@@ -8,45 +8,94 @@ import (
 	"github.com/cdimoush/vox/history"
 )
 
+const lsUsage = "Usage: vox ls [-n N] [--all] [--tag name]... [--json|--jsonl]"
+
 func cmdLs() error {
 	n := 20
+	var tags []string
+	var format outputFormat
 	args := os.Args[2:]
 
 	for i := 0; i < len(args); i++ {
+		if tag, skip, ok, err := parseTagFlag(args, i); ok {
+			if err != nil {
+				return fmt.Errorf("%v\n\n%s", err, lsUsage)
+			}
+			tags = addTags(tags, tag)
+			i += skip
+			continue
+		}
+		if parseFormatFlag(args[i], &format) {
+			continue
+		}
 		switch args[i] {
 		case "--all":
 			n = 0
 		case "-n":
 			if i+1 >= len(args) {
-				return fmt.Errorf("-n requires a number\n\nUsage: vox ls [-n N] [--all]")
+				return fmt.Errorf("-n requires a number\n\n%s", lsUsage)
 			}
 			i++
 			val, err := strconv.Atoi(args[i])
 			if err != nil || val < 1 {
-				return fmt.Errorf("invalid value for -n: %s\n\nUsage: vox ls [-n N] [--all]", args[i])
+				return fmt.Errorf("invalid value for -n: %s\n\n%s", args[i], lsUsage)
 			}
 			n = val
 		default:
-			return fmt.Errorf("unknown flag: %s\n\nUsage: vox ls [-n N] [--all]", args[i])
+			return fmt.Errorf("unknown flag: %s\n\n%s", args[i], lsUsage)
 		}
 	}
 
-	store := history.NewStore(history.DefaultPath())
-	entries, err := store.List(n)
+	store, err := openStore()
 	if err != nil {
 		return err
 	}
+	var entries []history.Entry
+	var positions []int
+	if len(tags) == 0 {
+		list, err := store.List(n)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			positions = append(positions, i+1)
+		}
+		entries = list
+	} else {
+		// Hits keep their unfiltered numbers, so they work with cp/show.
+		hits, err := store.Search(history.Query{Tags: tags}, n)
+		if err != nil {
+			return err
+		}
+		for _, h := range hits {
+			entries = append(entries, h.Entry)
+			positions = append(positions, h.Pos)
+		}
+	}
+	warnSkipped(store)
+
+	if format != formatTable {
+		var recs []entryRecord
+		for i, e := range entries {
+			recs = append(recs, newRecord(e, positions[i]))
+		}
+		return writeRecords(os.Stdout, format, recs)
+	}
 
 	if len(entries) == 0 {
-		fmt.Fprintln(os.Stderr, "No history yet.")
+		if len(tags) > 0 {
+			fmt.Fprintf(os.Stderr, "No entries tagged %s.\n", formatTags(tags))
+		} else {
+			fmt.Fprintln(os.Stderr, "No history yet.")
+		}
 		return nil
 	}
 
-	fmt.Fprintf(os.Stdout, "%-4s%-12s%s\n", "#", "When", "Text")
+	fmt.Fprintf(os.Stdout, "%-4s%-10s%-12s%s\n", "#", "ID", "When", "Text")
 	for i, e := range entries {
 		when := relativeTime(e.Timestamp)
 		text := truncate(e.Text, 60)
-		fmt.Fprintf(os.Stdout, "%-4d%-12s%s\n", i+1, when, text)
+		fmt.Fprintf(os.Stdout, "%-4d%-10s%-12s%s\n", positions[i], e.ID, when, text)
 	}
 
 	return nil
@@ -4,14 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/cdimoush/vox/budget"
+	"github.com/cdimoush/vox/config"
+	"github.com/cdimoush/vox/history"
 	"github.com/cdimoush/vox/transcribe"
 )
 
 const version = "v0.1.2"
 
 func main() {
-	var err error
+	err := parseGlobalFlags()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	if len(os.Args) < 2 {
 		err = run()
 	} else {
@@ -24,21 +33,53 @@ func main() {
 			err = cmdCp()
 		case "show":
 			err = cmdShow()
+		case "search":
+			err = cmdSearch()
+		case "tag":
+			err = cmdTag()
+		case "rm":
+			err = cmdRm()
+		case "edit":
+			err = cmdEdit()
+		case "stats":
+			err = cmdStats()
+		case "export":
+			err = cmdExport()
+		case "import":
+			err = cmdImport()
+		case "history":
+			err = cmdHistory()
+		case "prune":
+			err = cmdPrune()
 		case "clear":
 			err = cmdClear()
+		case "config":
+			err = cmdConfig()
+		case "doctor":
+			err = cmdDoctor()
 		case "login":
 			err = cmdLogin()
 		case "--version", "-v":
 			fmt.Println("vox " + version)
 			return
 		default:
-			fmt.Fprintf(os.Stderr, "Unknown command: %s\n\nUsage: vox [login|file|ls|cp|show|clear]\n", os.Args[1])
+			if strings.HasPrefix(os.Args[1], "-") {
+				err = run()
+				break
+			}
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n\nUsage: vox [login|file|ls|search|cp|show|tag|edit|rm|stats|export|import|prune|history|config|doctor|clear]\n", os.Args[1])
 			os.Exit(1)
 		}
 	}
 	if err != nil {
 		// In JSON mode, cmdFile already wrote JSON to stdout.
 		// Only print to stderr for non-JSON errors.
+		if errors.Is(err, history.ErrEncrypted) {
+			err = fmt.Errorf("%w\n\nSet history_encryption in ~/.vox/config (keyfile or passphrase) to read it", err)
+		}
+		if errors.Is(err, budget.ErrExceeded) {
+			err = fmt.Errorf("%w\n\nRun with --yes to transcribe anyway, or raise budget_daily/budget_monthly in ~/.vox/config", err)
+		}
 		var je *jsonError
 		if !errors.As(err, &je) {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -47,13 +88,117 @@ func main() {
 	}
 }
 
+// globalFlags map the flags every command accepts before its name to the
+// settings they override for this run. --profile selects a config
+// profile instead.
+var globalFlags = map[string]string{
+	"--profile":  "",
+	"--model":    "model",
+	"--language": "language",
+}
+
+// parseGlobalFlags takes the global flags (--flag value or --flag=value,
+// plus --no-clipboard) that come before the command name out of os.Args
+// and applies them. Arguments after the command name are the command's
+// own, so `vox tag 3 --note --model` sets the note to "--model"; "--"
+// ends the global flags too. Bare vox, which has no command name, and
+// vox login also take them among their own flags.
+func parseGlobalFlags() error {
+	args := []string{os.Args[0]}
+	i := 1
+	for ; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		skip, ok, err := parseGlobalFlag(os.Args, i)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			args = append(args, arg)
+			continue
+		}
+		i += skip
+	}
+	os.Args = append(args, os.Args[i:]...)
+	return checkProfile()
+}
+
+// parseGlobalFlag applies args[i] if it is a global flag. It reports
+// whether it was, and how many following arguments it consumed.
+func parseGlobalFlag(args []string, i int) (skip int, ok bool, err error) {
+	arg := args[i]
+	if arg == "--no-clipboard" {
+		config.Override("clipboard", "off", arg)
+		return 0, true, nil
+	}
+	name, value, hasValue := strings.Cut(arg, "=")
+	key, ok := globalFlags[name]
+	if !ok {
+		return 0, false, nil
+	}
+	if !hasValue {
+		if i+1 >= len(args) {
+			return 0, true, fmt.Errorf("%s requires a value", name)
+		}
+		skip, value = 1, args[i+1]
+	}
+	if name == "--profile" {
+		config.UseProfile(value)
+	} else {
+		config.Override(key, value, name)
+	}
+	return skip, true, nil
+}
+
+// checkProfile checks the active profile, from --profile or
+// $VOX_PROFILE: it must exist in the config files, except for vox login,
+// which creates it.
+func checkProfile() error {
+	name := config.Profile()
+	if name == "" {
+		return nil
+	}
+	if !config.ValidProfileName(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		return nil
+	}
+	profiles := config.Profiles()
+	if !slices.Contains(profiles, name) {
+		if len(profiles) == 0 {
+			return fmt.Errorf("unknown profile %q: ~/.vox/config has no profiles\n\nCreate it with: vox login --profile %s", name, name)
+		}
+		return fmt.Errorf("unknown profile %q (have: %s)\n\nCreate it with: vox login --profile %s", name, strings.Join(profiles, ", "), name)
+	}
+	return nil
+}
+
+// warnProject warns about settings a project file tried to set but
+// can't, such as the API key. vox and vox file call it once they have
+// loaded the settings, except in --json mode, where stderr stays quiet;
+// vox doctor reports the same in its config check.
+func warnProject() {
+	p, err := config.FindProject()
+	if err != nil || p == nil || len(p.Ignored) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "⚠ Ignoring %s in %s: project files may only set transcription settings\n", strings.Join(p.Ignored, ", "), p.Path)
+}
+
 // exitCode maps errors to exit codes:
 //
 //	0 = success
 //	1 = general error (file not found, bad args, bad format)
 //	2 = API error (transcription failed, rate limit, timeout)
 //	3 = no API key
+//	4 = over a spending limit (budget_daily, budget_monthly)
 func exitCode(err error) int {
+	if errors.Is(err, budget.ErrExceeded) {
+		return 4
+	}
 	if errors.Is(err, transcribe.ErrNoAPIKey) {
 		return 3
 	}
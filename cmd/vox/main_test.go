@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	t.Setenv("VOX_PROFILE", "")
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--no-clipboard", "ls"}, []string{"ls"}},
+		{[]string{"--language", "en", "ls", "-n", "3"}, []string{"ls", "-n", "3"}},
+		{[]string{"--language=en", "--ptt", "--tag", "idea"}, []string{"--ptt", "--tag", "idea"}},
+		// After the command name, flags belong to the command.
+		{[]string{"tag", "3", "--note", "--model"}, []string{"tag", "3", "--note", "--model"}},
+		{[]string{"search", "--language", "en"}, []string{"search", "--language", "en"}},
+		{[]string{"--", "--language", "en"}, []string{"--", "--language", "en"}},
+	}
+
+	saved := os.Args
+	t.Cleanup(func() { os.Args = saved })
+	for _, tt := range tests {
+		os.Args = append([]string{"vox"}, tt.args...)
+		if err := parseGlobalFlags(); err != nil {
+			t.Errorf("parseGlobalFlags(%q): %v", tt.args, err)
+			continue
+		}
+		if got := os.Args[1:]; !slices.Equal(got, tt.want) {
+			t.Errorf("parseGlobalFlags(%q) left %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	os.Args = []string{"vox", "--language"}
+	if err := parseGlobalFlags(); err == nil {
+		t.Error("parseGlobalFlags(--language) succeeded, want a missing-value error")
+	}
+}
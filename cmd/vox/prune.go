@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cdimoush/vox/config"
+)
+
+const pruneUsage = "Usage: vox prune [--max-age 90d] [--max-entries N] [--dry-run]"
+
+func cmdPrune() error {
+	r, err := configRetention()
+	if err != nil {
+		return err
+	}
+
+	dryRun := false
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--dry-run":
+			dryRun = true
+		case "--max-age":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--max-age requires a value\n\n%s", pruneUsage)
+			}
+			i++
+			if r.MaxAge, err = config.ParseAge(args[i]); err != nil {
+				return fmt.Errorf("%v\n\n%s", err, pruneUsage)
+			}
+		case "--max-entries":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--max-entries requires a number\n\n%s", pruneUsage)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid value for --max-entries: %s\n\n%s", args[i], pruneUsage)
+			}
+			r.MaxEntries = n
+		default:
+			return fmt.Errorf("unknown flag: %s\n\n%s", args[i], pruneUsage)
+		}
+	}
+	if r.IsZero() {
+		return fmt.Errorf("no retention limits: set history_max_age or history_max_entries in ~/.vox/config, or pass --max-age/--max-entries\n\n%s", pruneUsage)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	store.SetRetention(r)
+
+	now := time.Now()
+	var removed int
+	if dryRun {
+		all, _, err := store.ReadAll()
+		if err != nil {
+			return err
+		}
+		removed = len(all) - len(r.Apply(all, now))
+	} else if removed, err = store.Prune(now); err != nil {
+		return err
+	}
+
+	switch {
+	case removed == 0:
+		fmt.Fprintln(os.Stderr, "Nothing to prune.")
+	case dryRun:
+		fmt.Fprintf(os.Stderr, "Would remove %d entries.\n", removed)
+	default:
+		fmt.Fprintf(os.Stderr, "✓ Pruned %d entries\n", removed)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Push-to-talk modes for `vox --ptt`.
+const (
+	pttHold   = "hold"   // record while Space is held
+	pttToggle = "toggle" // Space starts, any key stops
+)
+
+// Terminals report no key-up events, so a held key is detected by its
+// autorepeat. The first repeat arrives after the keyboard's repeat delay
+// (typically 250–600ms); later ones arrive every ~30–50ms.
+var (
+	pttInitialHold = 700 * time.Millisecond
+	pttRepeatGap   = 200 * time.Millisecond
+)
+
+const (
+	keyCtrlC = 0x03
+	keyEsc   = 0x1b
+)
+
+// readStdin forwards everything read from stdin, chunk by chunk, until
+// EOF. In cooked mode a chunk is a line; in raw mode it is a keypress.
+func readStdin() <-chan []byte {
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 256)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				ch <- bytes.Clone(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// readLine collects chunks from readStdin up to and including a newline.
+func readLine(keys <-chan []byte) (string, error) {
+	var line []byte
+	for chunk := range keys {
+		line = append(line, chunk...)
+		if bytes.IndexByte(chunk, '\n') >= 0 {
+			return string(line), nil
+		}
+	}
+	if len(line) > 0 {
+		return string(line), nil
+	}
+	return "", fmt.Errorf("reading answer: %w", os.ErrClosed)
+}
+
+// rawTerminal puts stdin into raw mode so single keypresses arrive
+// immediately. The returned function restores the previous mode.
+func rawTerminal() (func(), error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("--ptt needs an interactive terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("entering raw mode: %w", err)
+	}
+	return func() { term.Restore(fd, state) }, nil
+}
+
+// waitForTalkKey blocks until Space is pressed. It returns false if the
+// user quits (q, Esc, Ctrl+C) or stdin closes first.
+func waitForTalkKey(keys <-chan []byte) bool {
+	for chunk := range keys {
+		for _, b := range chunk {
+			switch b {
+			case ' ':
+				return true
+			case 'q', keyEsc, keyCtrlC:
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// watchRelease calls stop when the talk key is released (hold mode) or
+// pressed again (toggle mode). Ctrl+C always stops. The recorder's cancel
+// function is the stop hook, as for Enter in normal mode. It also returns
+// once done is closed, when recording ended some other way, so that it
+// doesn't read keys meant for a later prompt.
+func watchRelease(mode string, keys <-chan []byte, done <-chan struct{}, stop func()) {
+	defer stop()
+	if mode == pttToggle {
+		// Skip autorepeat from the key that started recording.
+		quiet, last := pttInitialHold, time.Now()
+		for {
+			select {
+			case chunk, ok := <-keys:
+				if !ok || bytes.IndexByte(chunk, keyCtrlC) >= 0 || time.Since(last) >= quiet {
+					return
+				}
+				quiet, last = pttRepeatGap, time.Now()
+			case <-done:
+				return
+			}
+		}
+	}
+
+	timer := time.NewTimer(pttInitialHold)
+	defer timer.Stop()
+	for {
+		select {
+		case chunk, ok := <-keys:
+			if !ok || bytes.IndexByte(chunk, keyCtrlC) >= 0 {
+				return
+			}
+			timer.Reset(pttRepeatGap)
+		case <-timer.C:
+			return
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// withPTTTimings shortens the push-to-talk timeouts for a test.
+func withPTTTimings(t *testing.T) {
+	t.Helper()
+	initial, gap := pttInitialHold, pttRepeatGap
+	pttInitialHold, pttRepeatGap = 100*time.Millisecond, 60*time.Millisecond
+	t.Cleanup(func() { pttInitialHold, pttRepeatGap = initial, gap })
+}
+
+// stopAfter runs watchRelease and returns how long it took to call stop.
+func stopAfter(t *testing.T, mode string, keys <-chan []byte) time.Duration {
+	t.Helper()
+	start := time.Now()
+	stopped := make(chan struct{})
+	go watchRelease(mode, keys, nil, func() { close(stopped) })
+	select {
+	case <-stopped:
+		return time.Since(start)
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchRelease never called stop")
+		return 0
+	}
+}
+
+func TestWatchReleaseHoldTap(t *testing.T) {
+	withPTTTimings(t)
+	// A tap with no autorepeat stops after the initial hold window.
+	if d := stopAfter(t, pttHold, make(chan []byte)); d < pttInitialHold {
+		t.Errorf("stopped after %v, before initial hold %v", d, pttInitialHold)
+	}
+}
+
+func TestWatchReleaseHoldRepeats(t *testing.T) {
+	withPTTTimings(t)
+	keys := make(chan []byte)
+	go func() {
+		// Autorepeat for ~150ms, then release.
+		for range 15 {
+			time.Sleep(10 * time.Millisecond)
+			keys <- []byte(" ")
+		}
+	}()
+	if d := stopAfter(t, pttHold, keys); d < 150*time.Millisecond {
+		t.Errorf("stopped after %v while key was still held", d)
+	}
+}
+
+func TestWatchReleaseCtrlC(t *testing.T) {
+	withPTTTimings(t)
+	pttInitialHold = time.Hour
+	keys := make(chan []byte, 1)
+	keys <- []byte{keyCtrlC}
+	stopAfter(t, pttHold, keys)
+}
+
+func TestWatchReleaseToggle(t *testing.T) {
+	withPTTTimings(t)
+	keys := make(chan []byte)
+	go func() {
+		keys <- []byte(" ") // autorepeat from the start key: ignored
+		time.Sleep(150 * time.Millisecond)
+		keys <- []byte(" ") // second press: stop
+	}()
+	if d := stopAfter(t, pttToggle, keys); d < 150*time.Millisecond {
+		t.Errorf("toggle stopped after %v, on the start key's autorepeat", d)
+	}
+}
+
+func TestWatchReleaseDone(t *testing.T) {
+	withPTTTimings(t)
+	// Recording ended some other way: watchRelease returns without
+	// reading the next key, which answers a later prompt.
+	keys := make(chan []byte, 1)
+	done := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		watchRelease(pttToggle, keys, done, func() {})
+		close(returned)
+	}()
+	close(done)
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchRelease didn't return when done closed")
+	}
+	keys <- []byte("y\n")
+	if got := <-keys; string(got) != "y\n" {
+		t.Errorf("key after return = %q", got)
+	}
+}
+
+func TestWaitForTalkKey(t *testing.T) {
+	tests := []struct {
+		input []byte
+		want  bool
+	}{
+		{[]byte("x "), true},
+		{[]byte("q"), false},
+		{[]byte{keyEsc}, false},
+		{[]byte{keyCtrlC}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		keys := make(chan []byte, 1)
+		if tt.input != nil {
+			keys <- tt.input
+		}
+		close(keys)
+		if got := waitForTalkKey(keys); got != tt.want {
+			t.Errorf("waitForTalkKey(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	keys := make(chan []byte, 3)
+	keys <- []byte("  ")
+	keys <- []byte("y\n")
+	close(keys)
+	got, err := readLine(keys)
+	if err != nil || got != "  y\n" {
+		t.Errorf("readLine = %q, %v", got, err)
+	}
+}
+
+func TestParseRunFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    runOptions
+		wantErr bool
+	}{
+		{nil, runOptions{}, false},
+		{[]string{"--ptt"}, runOptions{ptt: pttHold}, false},
+		{[]string{"--ptt=toggle", "--skip-bad-audio"}, runOptions{ptt: pttToggle, skipBadAudio: true}, false},
+		{[]string{"--ptt=sideways"}, runOptions{}, true},
+		{[]string{"--tag", "Idea", "--tag=#robot", "--tag", "idea"}, runOptions{tags: []string{"idea", "robot"}}, false},
+		{[]string{"--tag"}, runOptions{}, true},
+		{[]string{"--tag", "two words"}, runOptions{}, true},
+		{[]string{"--bogus"}, runOptions{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRunFlags(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRunFlags(%v): err = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRunFlags(%v) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/cdimoush/vox/history"
+)
+
+// recordSchema is the version of the entry records printed by ls, show
+// and search with --json or --jsonl. New fields may appear without a
+// bump; removing, renaming or retyping a field bumps it.
+const recordSchema = 1
+
+// entryRecord is one history entry as printed for tools: the history line
+// fields, plus where the entry sits in vox ls order and, for search, what
+// matched.
+type entryRecord struct {
+	Schema int `json:"schema"`
+	Pos    int `json:"pos"` // 1-based position in vox ls order
+	history.Entry
+	Matches [][]int `json:"matches,omitempty"` // [start, end) UTF-8 byte offsets into text
+}
+
+// outputFormat is how ls, show and search print entries.
+type outputFormat int
+
+const (
+	formatTable outputFormat = iota
+	formatJSON               // a JSON array (an object for show)
+	formatJSONL              // one record per line
+)
+
+// parseFormatFlag reports whether arg is --json or --jsonl, and if so sets f.
+func parseFormatFlag(arg string, f *outputFormat) bool {
+	switch arg {
+	case "--json":
+		*f = formatJSON
+	case "--jsonl":
+		*f = formatJSONL
+	default:
+		return false
+	}
+	return true
+}
+
+// newRecord returns the record for e at position pos.
+func newRecord(e history.Entry, pos int) entryRecord {
+	return entryRecord{Schema: recordSchema, Pos: pos, Entry: e}
+}
+
+// writeRecords prints recs as a JSON array or as JSON lines.
+func writeRecords(w io.Writer, f outputFormat, recs []entryRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if f == formatJSONL {
+		for _, r := range recs {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if recs == nil {
+		recs = []entryRecord{}
+	}
+	return enc.Encode(recs)
+}
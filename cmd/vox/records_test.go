@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func TestWriteRecords(t *testing.T) {
+	recs := []entryRecord{
+		newRecord(history.Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "second <b>", DurationS: 2, ID: "bbbb2222", Tags: []string{"idea"}}, 1),
+		newRecord(history.Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "first", DurationS: 1, ID: "aaaa1111"}, 2),
+	}
+	recs[1].Matches = [][]int{{0, 5}}
+
+	const line1 = `{"schema":1,"pos":1,"ts":"2026-03-01T10:00:00Z","text":"second <b>","duration_s":2,"id":"bbbb2222","tags":["idea"]}`
+	const line2 = `{"schema":1,"pos":2,"ts":"2026-03-01T09:00:00Z","text":"first","duration_s":1,"id":"aaaa1111","matches":[[0,5]]}`
+	tests := []struct {
+		name   string
+		format outputFormat
+		recs   []entryRecord
+		want   string
+	}{
+		{"json", formatJSON, recs, "[" + line1 + "," + line2 + "]\n"},
+		{"jsonl", formatJSONL, recs, line1 + "\n" + line2 + "\n"},
+		{"json empty", formatJSON, nil, "[]\n"},
+		{"jsonl empty", formatJSONL, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeRecords(&buf, tt.format, tt.recs); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func cmdRm() error {
+	if len(os.Args) < 3 {
+		return fmt.Errorf("Usage: vox rm <n|id>...")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	entries, err := store.List(0)
+	if err != nil {
+		return err
+	}
+
+	// Resolve every reference against the same snapshot before removing
+	// anything, so `vox rm 2 3` means the entries ls showed as 2 and 3.
+	remove := map[string]bool{}
+	var removed []string
+	for _, ref := range os.Args[2:] {
+		i, err := history.Resolve(entries, ref)
+		if err != nil {
+			return err
+		}
+		if id := entries[i].ID; !remove[id] {
+			remove[id] = true
+			removed = append(removed, fmt.Sprintf("#%d (%s)%s", i+1, id, sharedSuffix(history.SharingID(entries, id))))
+		}
+	}
+
+	err = store.Rewrite(func(all []history.Entry) ([]history.Entry, error) {
+		kept := all[:0]
+		for _, e := range all {
+			if !remove[e.ID] {
+				kept = append(kept, e)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range removed {
+		fmt.Fprintf(os.Stderr, "✓ Removed %s\n", r)
+	}
+	return nil
+}
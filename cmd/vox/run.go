@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strings"
 	"sync"
@@ -20,13 +19,80 @@ import (
 // spinner frames for the transcription progress indicator.
 var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
+// runOptions holds the flags accepted by bare `vox`.
+type runOptions struct {
+	skipBadAudio bool
+	ptt          string   // "", pttHold or pttToggle
+	tags         []string // labels for the history entry
+}
+
+const runUsage = "Usage: vox [--ptt[=hold|toggle]] [--skip-bad-audio] [--tag name]... [--profile name] [--model name] [--language code] [--no-clipboard]"
+
+// parseRunFlags parses the flags given to bare `vox`, including global
+// flags that follow one of its own, as in `vox --tag idea --no-clipboard`.
+func parseRunFlags(args []string) (runOptions, error) {
+	var opts runOptions
+	for i := 0; i < len(args); i++ {
+		if skip, ok, err := parseGlobalFlag(args, i); ok {
+			if err != nil {
+				return opts, fmt.Errorf("%v\n\n%s", err, runUsage)
+			}
+			i += skip
+			continue
+		}
+		if tag, skip, ok, err := parseTagFlag(args, i); ok {
+			if err != nil {
+				return opts, fmt.Errorf("%v\n\n%s", err, runUsage)
+			}
+			opts.tags = addTags(opts.tags, tag)
+			i += skip
+			continue
+		}
+		switch arg := args[i]; arg {
+		case "--skip-bad-audio":
+			opts.skipBadAudio = true
+		case "--ptt", "--ptt=" + pttHold:
+			opts.ptt = pttHold
+		case "--ptt=" + pttToggle:
+			opts.ptt = pttToggle
+		default:
+			return opts, fmt.Errorf("unknown flag: %s\n\n%s", arg, runUsage)
+		}
+	}
+	return opts, nil
+}
+
 func run() error {
+	opts, err := parseRunFlags(os.Args[1:])
+	if err != nil {
+		return err
+	}
+	if err := checkProfile(); err != nil {
+		return err
+	}
+
 	// Check dependencies up front.
-	if config.FindAPIKey() == "" {
+	key, _, err := config.LookupAPIKey()
+	if err != nil {
+		return err
+	}
+	if key == "" {
 		return fmt.Errorf("OpenAI API key not found\n\nRun: vox login")
 	}
-	if _, err := exec.LookPath("rec"); err != nil {
-		return fmt.Errorf("rec (SoX) not found\n\nInstall with:\n  macOS:  brew install sox\n  Linux:  sudo apt-get install sox")
+	settings, err := config.Load()
+	if err != nil {
+		return err
+	}
+	warnProject()
+	rec, err := newRecorder(settings)
+	if err != nil {
+		return err
+	}
+	// Open history before recording or uploading, so a bad config or a
+	// passphrase prompt comes first.
+	store, err := openStore()
+	if err != nil {
+		return err
 	}
 	// Set up two-phase signal handling:
 	// Phase 1: Ctrl+C during recording stops recording → proceed to transcription
@@ -39,12 +105,9 @@ func run() error {
 	signal.Notify(sigCh, os.Interrupt)
 	defer signal.Stop(sigCh)
 
-	// Also stop recording when user presses Enter.
-	go func() {
-		buf := make([]byte, 1)
-		os.Stdin.Read(buf)
-		recCancel()
-	}()
+	// Stdin stops the recording (Enter, or the talk key in --ptt mode);
+	// later input answers prompts.
+	keys := readStdin()
 
 	// Forward first SIGINT to cancel recording context.
 	go func() {
@@ -56,14 +119,63 @@ func run() error {
 		recCancel()
 	}()
 
-	fmt.Fprintln(os.Stderr, "● Recording... (Enter to stop)")
-
-	result, err := recorder.Record(recCtx)
+	var result recorder.Result
+	if opts.ptt != "" {
+		restore, rawErr := rawTerminal()
+		if rawErr != nil {
+			return rawErr
+		}
+		if opts.ptt == pttHold {
+			fmt.Fprint(os.Stderr, "Hold Space to talk (q to quit)\r\n")
+		} else {
+			fmt.Fprint(os.Stderr, "Press Space to talk, any key to stop (q to quit)\r\n")
+		}
+		if !waitForTalkKey(keys) {
+			restore()
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return nil
+		}
+		released := make(chan struct{})
+		go func() {
+			watchRelease(opts.ptt, keys, recCtx.Done(), recCancel)
+			close(released)
+		}()
+		fmt.Fprint(os.Stderr, "○ Starting mic...")
+		result, err = rec.Record(recCtx, recorder.Options{Ready: func() {
+			fmt.Fprint(os.Stderr, "\r● Recording...   \r\n")
+		}})
+		// Stop watching keys before a prompt can read them.
+		recCancel()
+		<-released
+		restore()
+	} else {
+		go func() {
+			select {
+			case <-keys:
+			case <-recCtx.Done():
+			}
+			recCancel()
+		}()
+		// Only tell the user to speak once audio is actually flowing.
+		fmt.Fprint(os.Stderr, "○ Starting mic...")
+		result, err = rec.Record(recCtx, recorder.Options{Ready: func() {
+			fmt.Fprintln(os.Stderr, "\r● Recording... (Enter to stop)")
+		}})
+	}
 	if err != nil {
 		return fmt.Errorf("recording failed: %w", err)
 	}
 	defer os.Remove(result.FilePath)
 
+	// Don't pay for a muted mic: check levels before uploading.
+	var ask func() (string, error)
+	if isTerminal(os.Stdin) {
+		ask = func() (string, error) { return readLine(keys) }
+	}
+	if err := confirmAudio(result.Levels, opts.skipBadAudio, ask); err != nil {
+		return err
+	}
+
 	// Phase 2: second Ctrl+C aborts transcription.
 	txCtx, txCancel := context.WithCancel(context.Background())
 	defer txCancel()
@@ -97,7 +209,7 @@ func run() error {
 	}()
 
 	// Transcribe.
-	text, duration, err := transcribeWithContext(txCtx, result.FilePath)
+	tr, err := transcribeWithContext(txCtx, result.FilePath)
 	close(spinnerDone)
 	spinnerWg.Wait()
 
@@ -105,12 +217,14 @@ func run() error {
 		return fmt.Errorf("transcription failed: %w", err)
 	}
 
+	text := strings.TrimSpace(tr.Text)
+
 	// Print transcribed text in quotes.
-	fmt.Fprintf(os.Stderr, "\n\"%s\"\n\n", strings.TrimSpace(text))
+	fmt.Fprintf(os.Stderr, "\n\"%s\"\n\n", text)
 
 	// Copy to clipboard (best-effort).
-	if clipboard.Available() {
-		if err := clipboard.Write(strings.TrimSpace(text)); err != nil {
+	if settings.Clipboard && clipboard.Available() {
+		if err := clipboard.Write(text); err != nil {
 			fmt.Fprintf(os.Stderr, "⚠ Clipboard unavailable: %v\n", err)
 		} else {
 			fmt.Fprintln(os.Stderr, "✓ Copied to clipboard")
@@ -120,14 +234,12 @@ func run() error {
 	// Save to history. Prefer recorder duration; fall back to API-reported duration.
 	histDuration := result.Duration.Seconds()
 	if histDuration == 0 {
-		histDuration = duration
-	}
-	store := history.NewStore(history.DefaultPath())
-	entry := history.Entry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Text:      strings.TrimSpace(text),
-		DurationS: histDuration,
+		histDuration = tr.Duration
 	}
+	entry := newEntry(text, histDuration, tr)
+	recordSpend(entry, false)
+	entry.Source = history.SourceMic
+	entry.Tags = opts.tags
 	if err := store.Append(entry); err != nil {
 		return fmt.Errorf("saving history: %w", err)
 	}
@@ -135,24 +247,48 @@ func run() error {
 	return nil
 }
 
+// newRecorder returns the recorder named by the recorder setting, or the
+// first one installed.
+func newRecorder(s config.Settings) (recorder.Recorder, error) {
+	if s.Recorder != "" {
+		return recorder.Lookup(s.Recorder)
+	}
+	return recorder.Detect()
+}
+
 // transcribeWithContext runs transcription, passing the context through
 // to the transcribe package for cancellation support.
-func transcribeWithContext(ctx context.Context, filePath string) (string, float64, error) {
+func transcribeWithContext(ctx context.Context, filePath string) (transcribe.Result, error) {
 	type result struct {
-		text     string
-		duration float64
-		err      error
+		tr  transcribe.Result
+		err error
 	}
 	ch := make(chan result, 1)
 	go func() {
-		text, dur, err := transcribe.Transcribe(ctx, filePath)
-		ch <- result{text, dur, err}
+		tr, err := transcribe.Transcribe(ctx, filePath)
+		ch <- result{tr, err}
 	}()
 
 	select {
 	case <-ctx.Done():
-		return "", 0, ctx.Err()
+		return transcribe.Result{}, ctx.Err()
 	case r := <-ch:
-		return r.text, r.duration, r.err
+		return r.tr, r.err
+	}
+}
+
+// newEntry returns a history entry for a finished transcription of
+// seconds of audio, recording how it was transcribed.
+func newEntry(text string, seconds float64, tr transcribe.Result) history.Entry {
+	model := transcribe.ConfiguredModel()
+	return history.Entry{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Text:      text,
+		DurationS: seconds,
+		Provider:  transcribe.Provider,
+		Model:     model,
+		Language:  tr.Language,
+		Chunks:    tr.Chunks,
+		CostUSD:   transcribe.EstimateCost(model, seconds),
 	}
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cdimoush/vox/history"
+)
+
+const searchUsage = "Usage: vox search <query> [-r|--regex] [--since 7d] [--until 2026-03-01] [--min-duration 30] [--tag name]... [-n N] [--json|--jsonl]"
+
+// ANSI escapes used to highlight matches on a terminal.
+const (
+	hlStart = "\x1b[1;33m"
+	hlEnd   = "\x1b[0m"
+)
+
+func cmdSearch() error {
+	var (
+		q      history.Query
+		limit  int
+		format outputFormat
+		text   []string
+		now    = time.Now()
+	)
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		if tag, skip, ok, err := parseTagFlag(args, i); ok {
+			if err != nil {
+				return fmt.Errorf("%v\n\n%s", err, searchUsage)
+			}
+			q.Tags = addTags(q.Tags, tag)
+			i += skip
+			continue
+		}
+		if parseFormatFlag(args[i], &format) {
+			continue
+		}
+		switch args[i] {
+		case "-r", "--regex":
+			q.Regex = true
+		case "--since", "--until", "--min-duration", "-n":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a value\n\n%s", args[i], searchUsage)
+			}
+			flag, val := args[i], args[i+1]
+			i++
+			var err error
+			switch flag {
+			case "--since":
+				q.Since, err = parseTimeBound(val, now, false)
+			case "--until":
+				q.Until, err = parseTimeBound(val, now, true)
+			case "--min-duration":
+				q.MinDuration, err = parseSeconds(val)
+			case "-n":
+				if limit, err = strconv.Atoi(val); err == nil && limit < 1 {
+					err = fmt.Errorf("must be at least 1")
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %v\n\n%s", flag, err, searchUsage)
+			}
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				return fmt.Errorf("unknown flag: %s\n\n%s", args[i], searchUsage)
+			}
+			text = append(text, args[i])
+		}
+	}
+	if len(text) == 0 {
+		return fmt.Errorf("%s", searchUsage)
+	}
+	q.Text = strings.Join(text, " ")
+
+	if _, err := q.Compile(); err != nil {
+		return err
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	hits, err := store.Search(q, limit)
+	if err != nil {
+		return err
+	}
+	warnSkipped(store)
+	if format != formatTable {
+		var recs []entryRecord
+		for _, h := range hits {
+			r := newRecord(h.Entry, h.Pos)
+			r.Matches = h.Locs
+			recs = append(recs, r)
+		}
+		return writeRecords(os.Stdout, format, recs)
+	}
+	if len(hits) == 0 {
+		fmt.Fprintln(os.Stderr, "No matches.")
+		return nil
+	}
+
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	fmt.Fprintf(os.Stdout, "%-4s%-10s%-12s%s\n", "#", "ID", "When", "Text")
+	for _, h := range hits {
+		// Numbers are positions in `vox ls` order, so they work with cp and show.
+		e := h.Entry
+		fmt.Fprintf(os.Stdout, "%-4d%-10s%-12s%s\n", h.Pos, e.ID, relativeTime(e.Timestamp), snippet(e.Text, h.Locs, 60, color))
+	}
+	return nil
+}
+
+// snippet returns about width characters of text around the first match,
+// with newlines flattened to spaces. If color is set, every match inside
+// the window is highlighted with ANSI escapes; otherwise the text is
+// returned plain, as truncate would.
+func snippet(text string, locs [][]int, width int, color bool) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+
+	// Centre the window on the first match when it would fall off the end.
+	start, end := 0, len(text)
+	if len(locs) > 0 && locs[0][1] > width-3 {
+		start = locs[0][0] - width/3
+	}
+	if end-start > width {
+		end = start + width
+	}
+	start, end = runeStart(text, max(start, 0)), runeStart(text, min(end, len(text)))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	pos := start
+	for _, loc := range locs {
+		s, e := max(loc[0], pos), min(loc[1], end)
+		if !color || s >= e {
+			continue
+		}
+		b.WriteString(text[pos:s])
+		b.WriteString(hlStart + text[s:e] + hlEnd)
+		pos = e
+	}
+	b.WriteString(text[pos:end])
+	if end < len(text) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// runeStart moves byte offset i back to the start of the rune containing it.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("filler ", 20) + "the gantry collision boundary" + strings.Repeat(" tail", 20)
+	at := strings.Index(long, "gantry")
+
+	tests := []struct {
+		name  string
+		text  string
+		locs  [][]int
+		color bool
+		want  string
+	}{
+		{"short, no color", "hello world", [][]int{{6, 11}}, false, "hello world"},
+		{"short, highlighted", "hello world", [][]int{{6, 11}}, true, "hello " + hlStart + "world" + hlEnd},
+		{"newlines flattened", "a\nb", nil, false, "a b"},
+		{"no matches truncates like ls", strings.Repeat("x", 70), nil, false, strings.Repeat("x", 60) + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippet(tt.text, tt.locs, 60, tt.color); got != tt.want {
+				t.Errorf("snippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("late match is brought into view", func(t *testing.T) {
+		got := snippet(long, [][]int{{at, at + len("gantry")}}, 60, true)
+		if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") {
+			t.Errorf("expected ellipses on both ends, got %q", got)
+		}
+		if !strings.Contains(got, hlStart+"gantry"+hlEnd) {
+			t.Errorf("expected highlighted match, got %q", got)
+		}
+	})
+
+	t.Run("never splits a rune", func(t *testing.T) {
+		text := strings.Repeat("é", 40)
+		got := snippet(text, nil, 60, false)
+		if !strings.HasPrefix(got, strings.Repeat("é", 30)) || strings.ContainsRune(got, '�') {
+			t.Errorf("bad rune handling: %q", got)
+		}
+	})
+}
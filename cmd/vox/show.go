@@ -3,33 +3,91 @@ package main
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 
 	"github.com/cdimoush/vox/history"
 )
 
+const showUsage = "Usage: vox show <n|id> [--json|--jsonl]"
+
 func cmdShow() error {
-	if len(os.Args) < 3 {
-		return fmt.Errorf("Usage: vox show <n>")
+	var ref string
+	var format outputFormat
+	for _, arg := range os.Args[2:] {
+		switch {
+		case parseFormatFlag(arg, &format):
+		case ref == "" && !strings.HasPrefix(arg, "-"):
+			ref = arg
+		default:
+			return fmt.Errorf("unexpected argument: %s\n\n%s", arg, showUsage)
+		}
+	}
+	if ref == "" {
+		return fmt.Errorf("%s", showUsage)
 	}
 
-	n, err := strconv.Atoi(os.Args[2])
+	store, err := openStore()
 	if err != nil {
-		return fmt.Errorf("invalid entry number: %s", os.Args[2])
+		return err
 	}
-
-	store := history.NewStore(history.DefaultPath())
-	entries, err := store.List(0)
+	entry, pos, err := findEntry(store, ref)
 	if err != nil {
 		return err
 	}
 
-	if n < 1 || n > len(entries) {
-		return fmt.Errorf("entry #%d not found (have %d entries)", n, len(entries))
+	if format != formatTable {
+		// One record is the same as a JSON value or a JSON line.
+		return writeRecords(os.Stdout, formatJSONL, []entryRecord{newRecord(entry, pos)})
 	}
 
-	entry := entries[n-1]
-	fmt.Fprintf(os.Stderr, "[%s]\n\n", relativeTime(entry.Timestamp))
+	header := relativeTime(entry.Timestamp) + " · " + entry.ID
+	if len(entry.Tags) > 0 {
+		header += " · " + formatTags(entry.Tags)
+	}
+	fmt.Fprintf(os.Stderr, "[%s]\n", header)
+	if meta := describeEntry(entry); meta != "" {
+		fmt.Fprintln(os.Stderr, meta)
+	}
+	if entry.Note != "" {
+		fmt.Fprintf(os.Stderr, "Note: %s\n", entry.Note)
+	}
+	fmt.Fprintln(os.Stderr)
 	fmt.Println(entry.Text)
 	return nil
 }
+
+// describeEntry summarizes where an entry's audio came from and how it
+// was transcribed, e.g. "file /tmp/memo.m4a · openai/gpt-4o-mini-transcribe
+// · 12m04s · 3 chunks · ~$0.0362". Fields the entry lacks (older entries
+// have none of them) are left out.
+func describeEntry(e history.Entry) string {
+	var parts []string
+	switch {
+	case e.Source == history.SourceFile && e.File != "":
+		parts = append(parts, "file "+e.File)
+	case e.Source != "":
+		parts = append(parts, e.Source)
+	}
+	switch {
+	case e.Provider != "" && e.Model != "":
+		parts = append(parts, e.Provider+"/"+e.Model)
+	case e.Model != "":
+		parts = append(parts, e.Model)
+	}
+	if e.Language != "" {
+		parts = append(parts, e.Language)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	if e.DurationS > 0 {
+		parts = append(parts, formatDuration(e.DurationS))
+	}
+	if e.Chunks > 1 {
+		parts = append(parts, fmt.Sprintf("%d chunks", e.Chunks))
+	}
+	if e.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("~$%.4f", e.CostUSD))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func TestDescribeEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		e    history.Entry
+		want string
+	}{
+		{"old entry", history.Entry{Text: "x", DurationS: 12}, ""},
+		{"mic", history.Entry{DurationS: 42, Source: "mic", Provider: "openai", Model: "gpt-4o-mini-transcribe", Chunks: 1, CostUSD: 0.0021},
+			"mic · openai/gpt-4o-mini-transcribe · 42s · ~$0.0021"},
+		{"chunked file", history.Entry{DurationS: 724, Source: "file", File: "/tmp/memo.m4a", Model: "m", Language: "english", Chunks: 3},
+			"file /tmp/memo.m4a · m · english · 12m04s · 3 chunks"},
+		{"stdin", history.Entry{Source: "stdin"}, "stdin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeEntry(tt.e); got != tt.want {
+				t.Errorf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdimoush/vox/history"
+	"github.com/cdimoush/vox/transcribe"
+)
+
+const statsUsage = "Usage: vox stats [--since 30d] [--until 2026-03-01] [--by day|week|month] [--json]"
+
+// statsLongest is how many of the longest entries vox stats lists.
+const statsLongest = 5
+
+// usageStats is the vox stats report. It is also the --json output.
+type usageStats struct {
+	Since          *time.Time    `json:"since,omitempty"`
+	Until          *time.Time    `json:"until,omitempty"`
+	By             string        `json:"by,omitempty"`
+	Entries        int           `json:"entries"`
+	Minutes        float64       `json:"minutes"`
+	CostUSD        float64       `json:"cost_usd"`
+	WordsPerMinute float64       `json:"words_per_minute"`
+	Periods        []statsPeriod `json:"periods,omitempty"`
+	Longest        []statsEntry  `json:"longest"`
+	UnpricedModels []string      `json:"unpriced_models,omitempty"`
+}
+
+// statsPeriod is one row of a --by breakdown.
+type statsPeriod struct {
+	Period  string  `json:"period"` // 2026-03-01, 2026-W09 or 2026-03
+	Entries int     `json:"entries"`
+	Minutes float64 `json:"minutes"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// statsEntry is one of the longest entries.
+type statsEntry struct {
+	ID        string  `json:"id"`
+	Timestamp string  `json:"ts"`
+	DurationS float64 `json:"duration_s"`
+	Text      string  `json:"text"`
+}
+
+func cmdStats() error {
+	var (
+		q        history.Query
+		by       string
+		jsonMode bool
+		now      = time.Now()
+	)
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--json":
+			jsonMode = true
+		case "--since", "--until", "--by":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a value\n\n%s", args[i], statsUsage)
+			}
+			flag, val := args[i], args[i+1]
+			i++
+			var err error
+			switch flag {
+			case "--since":
+				q.Since, err = parseTimeBound(val, now, false)
+			case "--until":
+				q.Until, err = parseTimeBound(val, now, true)
+			case "--by":
+				if periodKey(val, now) == "" {
+					err = fmt.Errorf("want day, week or month")
+				}
+				by = val
+			}
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %v\n\n%s", flag, err, statsUsage)
+			}
+		default:
+			return fmt.Errorf("unknown argument: %s\n\n%s", args[i], statsUsage)
+		}
+	}
+
+	matcher, err := q.Compile()
+	if err != nil {
+		return err
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	all, _, err := store.ReadAll()
+	if err != nil {
+		return err
+	}
+	var entries []history.Entry
+	for _, e := range all {
+		if _, ok := matcher.Match(e); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	s := computeStats(entries, by, transcribe.PricePerMinute)
+	if !q.Since.IsZero() {
+		s.Since = &q.Since
+	}
+	if !q.Until.IsZero() {
+		s.Until = &q.Until
+	}
+	if len(s.UnpricedModels) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠ No price for %s; counted as $0. Set price.<model>=<USD per minute> in ~/.vox/config\n", strings.Join(s.UnpricedModels, ", "))
+	}
+
+	if jsonMode {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	if s.Entries == 0 {
+		fmt.Fprintln(os.Stderr, "No history in this range.")
+		return nil
+	}
+	return writeStats(os.Stdout, s)
+}
+
+// computeStats aggregates entries (oldest first). Cost is recomputed from
+// each entry's duration and model with price, so a changed price table
+// applies to old entries too; entries from before models were recorded
+// are priced as transcribe.Model, which produced them.
+func computeStats(entries []history.Entry, by string, price func(model string) (float64, bool)) usageStats {
+	s := usageStats{By: by, Longest: []statsEntry{}}
+	var words int
+	var spokenMinutes float64
+	periods := map[string]*statsPeriod{}
+	unpriced := map[string]bool{}
+
+	for _, e := range entries {
+		minutes := e.DurationS / 60
+		model := e.Model
+		if model == "" {
+			model = transcribe.Model
+		}
+		perMinute, ok := price(model)
+		if !ok && !unpriced[model] {
+			unpriced[model] = true
+			s.UnpricedModels = append(s.UnpricedModels, model)
+		}
+		cost := minutes * perMinute
+
+		s.Entries++
+		s.Minutes += minutes
+		s.CostUSD += cost
+		if e.DurationS > 0 {
+			words += len(strings.Fields(e.Text))
+			spokenMinutes += minutes
+		}
+
+		if by != "" {
+			t, err := e.Time()
+			if err != nil {
+				continue
+			}
+			key := periodKey(by, t.Local())
+			p := periods[key]
+			if p == nil {
+				p = &statsPeriod{Period: key}
+				periods[key] = p
+			}
+			p.Entries++
+			p.Minutes += minutes
+			p.CostUSD += cost
+		}
+	}
+	if spokenMinutes > 0 {
+		s.WordsPerMinute = float64(words) / spokenMinutes
+	}
+
+	for _, p := range periods {
+		s.Periods = append(s.Periods, *p)
+	}
+	sort.Slice(s.Periods, func(i, j int) bool { return s.Periods[i].Period < s.Periods[j].Period })
+
+	longest := slices.Clone(entries)
+	sort.SliceStable(longest, func(i, j int) bool { return longest[i].DurationS > longest[j].DurationS })
+	for _, e := range longest[:min(statsLongest, len(longest))] {
+		if e.DurationS <= 0 {
+			break
+		}
+		s.Longest = append(s.Longest, statsEntry{ID: e.ID, Timestamp: e.Timestamp, DurationS: e.DurationS, Text: e.Text})
+	}
+	return s
+}
+
+// periodKey names the day, ISO week or month containing t, or returns ""
+// for an unknown period. Keys sort chronologically as strings.
+func periodKey(by string, t time.Time) string {
+	switch by {
+	case "day":
+		return t.Format("2006-01-02")
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	}
+	return ""
+}
+
+// writeStats prints the report as plain-text tables.
+func writeStats(w io.Writer, s usageStats) error {
+	if len(s.Periods) > 0 {
+		fmt.Fprintf(w, "%-12s%8s%10s%11s\n", strings.ToUpper(s.By[:1])+s.By[1:], "Entries", "Minutes", "Est. cost")
+		for _, p := range s.Periods {
+			fmt.Fprintf(w, "%-12s%8d%10.1f%11s\n", p.Period, p.Entries, p.Minutes, formatUSD(p.CostUSD))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Entries:           %d\n", s.Entries)
+	fmt.Fprintf(w, "Minutes:           %.1f\n", s.Minutes)
+	fmt.Fprintf(w, "Estimated cost:    %s\n", formatUSD(s.CostUSD))
+	if s.WordsPerMinute > 0 {
+		fmt.Fprintf(w, "Words per minute:  %.0f\n", s.WordsPerMinute)
+	}
+	if len(s.Longest) > 0 {
+		fmt.Fprintln(w, "\nLongest:")
+		for _, e := range s.Longest {
+			fmt.Fprintf(w, "  %-10s%-8s%-10s%s\n", e.ID, formatDuration(e.DurationS), relativeTime(e.Timestamp), truncate(e.Text, 50))
+		}
+	}
+	return nil
+}
+
+// formatUSD renders a dollar amount, with extra precision below a cent.
+func formatUSD(v float64) string {
+	if v > 0 && v < 0.01 {
+		return fmt.Sprintf("$%.4f", v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
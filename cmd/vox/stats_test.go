@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cdimoush/vox/history"
+)
+
+func TestComputeStats(t *testing.T) {
+	price := func(model string) (float64, bool) {
+		switch model {
+		case "gpt-4o-mini-transcribe":
+			return 0.003, true
+		case "gpt-4o-transcribe":
+			return 0.006, true
+		}
+		return 0, false
+	}
+	entries := []history.Entry{
+		{ID: "a", Timestamp: "2026-03-01T12:00:00Z", Text: "one two three four five six", DurationS: 60},
+		{ID: "b", Timestamp: "2026-03-02T12:00:00Z", Text: "seven eight", DurationS: 120, Model: "gpt-4o-transcribe"},
+		{ID: "c", Timestamp: "2026-04-01T12:00:00Z", Text: "typed in, no audio"},
+		{ID: "d", Timestamp: "2026-04-02T12:00:00Z", Text: "nine", DurationS: 30, Model: "mystery"},
+	}
+
+	s := computeStats(entries, "month", price)
+	if s.Entries != 4 || s.Minutes != 3.5 {
+		t.Errorf("entries %d, minutes %v; want 4, 3.5", s.Entries, s.Minutes)
+	}
+	if want := 0.003 + 2*0.006; math.Abs(s.CostUSD-want) > 1e-9 {
+		t.Errorf("cost %v, want %v", s.CostUSD, want)
+	}
+	if want := 9 / 3.5; math.Abs(s.WordsPerMinute-want) > 1e-9 {
+		t.Errorf("words per minute %v, want %v", s.WordsPerMinute, want)
+	}
+	if !reflect.DeepEqual(s.UnpricedModels, []string{"mystery"}) {
+		t.Errorf("unpriced %v, want [mystery]", s.UnpricedModels)
+	}
+
+	var periods []string
+	for _, p := range s.Periods {
+		periods = append(periods, p.Period)
+	}
+	if !reflect.DeepEqual(periods, []string{"2026-03", "2026-04"}) || s.Periods[0].Entries != 2 || s.Periods[0].Minutes != 3 {
+		t.Errorf("periods %+v", s.Periods)
+	}
+
+	var longest []string
+	for _, e := range s.Longest {
+		longest = append(longest, e.ID)
+	}
+	if !reflect.DeepEqual(longest, []string{"b", "a", "d"}) {
+		t.Errorf("longest %v, want [b a d]", longest)
+	}
+
+	if s := computeStats(nil, "", price); s.Entries != 0 || s.Longest == nil || s.Periods != nil {
+		t.Errorf("empty history: %+v", s)
+	}
+}
+
+func TestPeriodKey(t *testing.T) {
+	at := time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC) // a Friday in ISO week 53 of 2026
+	tests := []struct{ by, want string }{
+		{"day", "2027-01-01"},
+		{"week", "2026-W53"},
+		{"month", "2027-01"},
+		{"year", ""},
+	}
+	for _, tt := range tests {
+		if got := periodKey(tt.by, at); got != tt.want {
+			t.Errorf("periodKey(%q) = %q, want %q", tt.by, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/cdimoush/vox/config"
+	"github.com/cdimoush/vox/history"
+)
+
+// History encryption modes, as set by history_encryption in ~/.vox/config.
+const (
+	encryptKeyFile    = "keyfile"
+	encryptPassphrase = "passphrase"
+)
+
+// History backends, as set by history_backend in ~/.vox/config.
+const (
+	backendJSONL  = "jsonl"
+	backendSQLite = "sqlite"
+)
+
+// openStore returns the configured history backend with the retention
+// policy and encryption from ~/.vox/config applied.
+func openStore() (history.Backend, error) {
+	r, err := configRetention()
+	if err != nil {
+		return nil, err
+	}
+	kind, err := configBackend()
+	if err != nil {
+		return nil, err
+	}
+	if kind == backendSQLite {
+		if mode := config.Get("history_encryption"); mode != "" && mode != "off" {
+			return nil, fmt.Errorf("history_encryption isn't supported with history_backend=sqlite\n\nRun vox history decrypt before migrating, or use the jsonl backend")
+		}
+		db := history.NewSQLite(sqlitePath())
+		db.SetRetention(r)
+		return db, nil
+	}
+
+	store := history.NewStore(historyPath())
+	store.SetRetention(r)
+	c, err := configCipher()
+	if err != nil {
+		return nil, err
+	}
+	store.SetCipher(c)
+	return store, nil
+}
+
+// configBackend returns the history_backend setting, jsonl by default.
+func configBackend() (string, error) {
+	v, err := config.Checked("history_backend")
+	return v.Value, err
+}
+
+// configCipher returns the history cipher for the history_encryption
+// setting, or nil if history isn't encrypted.
+func configCipher() (*history.Cipher, error) {
+	v, err := config.Checked("history_encryption")
+	if err != nil {
+		return nil, err
+	}
+	switch v.Value {
+	case encryptKeyFile:
+		key, err := readKeyFile()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("history is encrypted with a key file, but %s is missing\n\nRestore it from your backup", keyFilePath())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return history.NewKeyCipher(key)
+	case encryptPassphrase:
+		s := config.Lookup("history_salt")
+		if s.Value == "" {
+			return nil, fmt.Errorf("history_encryption is passphrase, but history_salt isn't set")
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(s.Value)
+		if err != nil || len(salt) == 0 {
+			return nil, fmt.Errorf("history_salt in %s is invalid", s.Source)
+		}
+		pass, err := historyPassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		return history.NewPassphraseCipher(pass, salt)
+	}
+	return nil, nil
+}
+
+// historyPath returns the JSONL history file: the history_path setting,
+// or ~/.vox/history.jsonl.
+func historyPath() string {
+	if p := config.Get("history_path"); p != "" {
+		return config.ExpandHome(p)
+	}
+	return history.DefaultPath()
+}
+
+// sqlitePath returns the SQLite history database, history.db next to
+// historyPath.
+func sqlitePath() string {
+	return filepath.Join(filepath.Dir(historyPath()), "history.db")
+}
+
+// keyFilePath returns the history key file, which sits next to the
+// history itself.
+func keyFilePath() string {
+	return filepath.Join(filepath.Dir(historyPath()), "history.key")
+}
+
+// readKeyFile reads the base64 history key from keyFilePath.
+func readKeyFile() ([]byte, error) {
+	data, err := os.ReadFile(keyFilePath())
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid key: %w", keyFilePath(), err)
+	}
+	return key, nil
+}
+
+// historyPassphrase returns $VOX_HISTORY_PASSPHRASE or prompts for the
+// passphrase on the terminal, twice if confirm is set.
+func historyPassphrase(confirm bool) (string, error) {
+	if p := os.Getenv("VOX_HISTORY_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("history is encrypted with a passphrase\n\nSet VOX_HISTORY_PASSPHRASE, or run vox from a terminal to be asked for it")
+	}
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	pass, err := read("History passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	if pass == "" {
+		return "", fmt.Errorf("no passphrase entered")
+	}
+	if confirm {
+		again, err := read("Repeat passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("reading passphrase: %w", err)
+		}
+		if again != pass {
+			return "", fmt.Errorf("passphrases don't match")
+		}
+	}
+	return pass, nil
+}
+
+// configRetention reads the history_max_age and history_max_entries
+// settings.
+func configRetention() (history.Retention, error) {
+	var r history.Retention
+	var err error
+	if r.MaxAge, err = config.Age("history_max_age"); err != nil {
+		return r, err
+	}
+	if r.MaxEntries, err = config.Count("history_max_entries"); err != nil {
+		return r, err
+	}
+	return r, nil
+}
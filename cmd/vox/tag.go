@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cdimoush/vox/history"
+)
+
+const tagUsage = "Usage: vox tag <n|id> [tag...] [--remove tag...] [--note text]"
+
+func cmdTag() error {
+	if len(os.Args) < 3 {
+		return fmt.Errorf("%s", tagUsage)
+	}
+
+	var add, remove []string
+	var note *string
+	removing := false
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--remove":
+			removing = true
+		case arg == "--note":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--note requires a value\n\n%s", tagUsage)
+			}
+			i++
+			n := strings.TrimSpace(args[i])
+			note = &n
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown flag: %s\n\n%s", arg, tagUsage)
+		default:
+			tag := history.NormalizeTag(arg)
+			if tag == "" {
+				return fmt.Errorf("invalid tag %q: tags can't be empty or contain spaces or commas", arg)
+			}
+			if removing {
+				remove = append(remove, tag)
+			} else {
+				add = append(add, tag)
+			}
+		}
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	entry, n, err := findEntry(store, os.Args[2])
+	if err != nil {
+		return err
+	}
+
+	if len(add) > 0 || len(remove) > 0 || note != nil {
+		updated, err := store.Update(entry.ID, func(e *history.Entry) {
+			e.Tags = addTags(e.Tags, add...)
+			e.Tags = slices.DeleteFunc(e.Tags, func(t string) bool { return slices.Contains(remove, t) })
+			if note != nil {
+				e.Note = *note
+			}
+			entry = *e
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✓ Updated #%d (%s)%s\n", n, entry.ID, sharedSuffix(updated))
+	}
+
+	fmt.Fprintf(os.Stdout, "%s\n", formatTags(entry.Tags))
+	if entry.Note != "" {
+		fmt.Fprintf(os.Stdout, "Note: %s\n", entry.Note)
+	}
+	return nil
+}
+
+// addTags appends the tags not already in tags.
+func addTags(tags []string, add ...string) []string {
+	for _, t := range add {
+		if !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// formatTags renders tags as "#a #b", or "(no tags)".
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "(no tags)"
+	}
+	return "#" + strings.Join(tags, " #")
+}
+
+// parseTagFlag handles a --tag flag at args[i], in either the "--tag x"
+// or "--tag=x" form. It returns the normalized tag, how many extra
+// arguments were consumed, and whether args[i] was a --tag flag at all.
+func parseTagFlag(args []string, i int) (tag string, skip int, ok bool, err error) {
+	arg := args[i]
+	switch {
+	case arg == "--tag":
+		if i+1 >= len(args) {
+			return "", 0, true, fmt.Errorf("--tag requires a value")
+		}
+		arg, skip = args[i+1], 1
+	case strings.HasPrefix(arg, "--tag="):
+		arg = strings.TrimPrefix(arg, "--tag=")
+	default:
+		return "", 0, false, nil
+	}
+	if tag = history.NormalizeTag(arg); tag == "" {
+		return "", 0, true, fmt.Errorf("invalid tag %q: tags can't be empty or contain spaces or commas", arg)
+	}
+	return tag, skip, true, nil
+}
@@ -2,7 +2,10 @@
 //
 // Key discovery order:
 //  1. OPENAI_API_KEY environment variable
-//  2. ~/.vox/config file (written by vox login)
+//  2. vox's own storage, for the active profile and then the top level:
+//     the output of credential_command, the system keyring (with
+//     credential_store=keyring, as vox login sets), then OPENAI_API_KEY
+//     in ~/.vox/config. The XDG config file holds settings, not the key
 //  3. Shell profile files: ~/.bashrc, ~/.zshrc, ~/.bash_profile, ~/.profile
 package config
 
@@ -11,6 +14,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -21,37 +25,86 @@ const (
 
 // FindAPIKey returns the OpenAI API key by searching in priority order:
 //  1. OPENAI_API_KEY env var
-//  2. ~/.vox/config
+//  2. credential_command, the system keyring, then ~/.vox/config (the
+//     active profile, then the top level)
 //  3. Shell profile files (~/.bashrc, ~/.zshrc, ~/.bash_profile, ~/.profile)
 //
-// Returns an empty string if no key is found anywhere.
+// Returns an empty string if no key is found anywhere, or if
+// credential_command fails (see LookupAPIKey).
 func FindAPIKey() string {
+	key, _, _ := LookupAPIKey()
+	return key
+}
+
+// LookupAPIKey is FindAPIKey, also returning where the key came from:
+// "env OPENAI_API_KEY", "credential_command", "keyring (default)" or
+// "keyring (profile/work)", ~/.vox/config as Lookup names it, or a shell
+// profile. A credential_command or keyring that fails is an error, rather
+// than a reason to look further.
+func LookupAPIKey() (key, source string, err error) {
 	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
-		return key
+		return key, SourceEnv + " OPENAI_API_KEY", nil
 	}
-	if key := keyFromVoxConfig(); key != "" {
-		return key
+	if command := Get("credential_command"); command != "" {
+		key, err := keyFromCommand(command)
+		return key, "credential_command", err
 	}
-	if key := keyFromShellProfiles(); key != "" {
-		return key
+	if key, source, err := keyFromVoxConfig(); key != "" || err != nil {
+		return key, source, err
 	}
-	return ""
+	if key, path := keyFromShellProfiles(); key != "" {
+		return key, displayPath(path), nil
+	}
+	return "", "", nil
 }
 
-// keyFromVoxConfig reads OPENAI_API_KEY from ~/.vox/config.
-func keyFromVoxConfig() string {
+// keyFromVoxConfig reads the key from the keyring, if credential_store is
+// keyring, or ~/.vox/config: the active profile's, then the top-level
+// one. A keyring that is missing or can't be read is an error: vox login
+// took the key out of the file when it put it there.
+func keyFromVoxConfig() (key, source string, err error) {
 	path, err := voxConfigPath()
 	if err != nil {
-		return ""
+		return "", "", nil
+	}
+	var kr keyring
+	if Get("credential_store") == StoreKeyring {
+		kr, err = systemKeyring()
+		if err != nil {
+			return "", "keyring", fmt.Errorf("credential_store is keyring: %w", err)
+		}
+	}
+	sections := []string{""}
+	if p := Profile(); p != "" {
+		sections = []string{p, ""}
+	}
+	for _, section := range sections {
+		if kr != nil {
+			account := keyringAccount(section)
+			key, err := keyFromKeyring(kr, account)
+			if err != nil {
+				return "", "keyring (" + account + ")", fmt.Errorf("reading the key from %s: %w", kr.Name(), err)
+			}
+			if key != "" {
+				return key, "keyring (" + account + ")", nil
+			}
+		}
+		if key := readKeyFromFile(path, section, "OPENAI_API_KEY"); key != "" {
+			if section != "" {
+				return key, displayPath(path) + " [profile " + section + "]", nil
+			}
+			return key, displayPath(path), nil
+		}
 	}
-	return readKeyFromFile(path, "OPENAI_API_KEY")
+	return "", "", nil
 }
 
-// keyFromShellProfiles scans common shell profile files for an exported OPENAI_API_KEY.
-func keyFromShellProfiles() string {
+// keyFromShellProfiles scans common shell profile files for an exported
+// OPENAI_API_KEY, returning it and the file it's in.
+func keyFromShellProfiles() (key, path string) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return ""
+		return "", ""
 	}
 	profiles := []string{
 		filepath.Join(home, ".bashrc"),
@@ -61,10 +114,10 @@ func keyFromShellProfiles() string {
 	}
 	for _, p := range profiles {
 		if key := parseExportFromFile(p, "OPENAI_API_KEY"); key != "" {
-			return key
+			return key, p
 		}
 	}
-	return ""
+	return "", ""
 }
 
 // parseExportFromFile scans a shell script file for:
@@ -98,8 +151,10 @@ func parseExportFromFile(path, key string) string {
 	return ""
 }
 
-// readKeyFromFile reads a key=value config file and returns the value for the given key.
-func readKeyFromFile(path, key string) string {
+// readKeyFromFile reads a key=value config file and returns the value for
+// the given key in section ("" for the top level, before any [profile]
+// header).
+func readKeyFromFile(path, section, key string) string {
 	f, err := os.Open(path)
 	if err != nil {
 		return ""
@@ -107,10 +162,15 @@ func readKeyFromFile(path, key string) string {
 	defer f.Close()
 
 	prefix := key + "="
+	current := ""
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "#") {
+		if name, ok := parseSection(line); ok {
+			current = name
+			continue
+		}
+		if current != section || strings.HasPrefix(line, "#") {
 			continue
 		}
 		if strings.HasPrefix(line, prefix) {
@@ -124,8 +184,23 @@ func readKeyFromFile(path, key string) string {
 	return ""
 }
 
-// SaveAPIKey writes the API key to ~/.vox/config.
-func SaveAPIKey(key string) error {
+// Get returns the value of key, or "" if it is unset. It is looked up
+// as Lookup does, but without falling back to a default.
+func Get(key string) string {
+	v, _ := lookup(key)
+	return v.Value
+}
+
+// Set writes a top-level key=value to ~/.vox/config. See SetIn.
+func Set(key, value string) error {
+	return SetIn("", key, value)
+}
+
+// SetIn writes key=value to section of ~/.vox/config ("" for the top
+// level, otherwise a profile, which is created if needed), replacing any
+// existing line for key there. An empty value removes the key. The file
+// keeps mode 0600 since it may hold the API key.
+func SetIn(section, key, value string) error {
 	path, err := voxConfigPath()
 	if err != nil {
 		return err
@@ -134,20 +209,39 @@ func SaveAPIKey(key string) error {
 		return fmt.Errorf("creating ~/.vox: %w", err)
 	}
 
-	// Read existing config, replace or append OPENAI_API_KEY line.
 	lines := []string{}
 	existing, err := os.ReadFile(path)
-	if err == nil {
-		for _, line := range strings.Split(string(existing), "\n") {
-			if !strings.HasPrefix(strings.TrimSpace(line), "OPENAI_API_KEY=") {
-				lines = append(lines, line)
+	if err == nil && strings.TrimSpace(string(existing)) != "" {
+		lines = strings.Split(strings.TrimSuffix(string(existing), "\n"), "\n")
+	}
+
+	// Drop the key's line from the section, then add the new one at the
+	// end of the section, ahead of any blank lines separating it from the
+	// next.
+	start, end, found := sectionBounds(lines, section)
+	var body []string
+	for _, line := range lines[start:end] {
+		if !strings.HasPrefix(strings.TrimSpace(line), key+"=") {
+			body = append(body, line)
+		}
+	}
+	if value != "" {
+		at := len(body)
+		for at > 0 && strings.TrimSpace(body[at-1]) == "" {
+			at--
+		}
+		body = slices.Insert(body, at, key+"="+value)
+		if !found {
+			if len(lines) > 0 {
+				body = append([]string{""}, body...)
 			}
+			body = slices.Insert(body, len(body)-1, "[profile "+section+"]")
 		}
 	}
-	lines = append(lines, "OPENAI_API_KEY="+key)
+	lines = slices.Concat(lines[:start], body, lines[end:])
 
 	content := strings.Join(lines, "\n")
-	if !strings.HasSuffix(content, "\n") {
+	if content != "" && !strings.HasSuffix(content, "\n") {
 		content += "\n"
 	}
 	return os.WriteFile(path, []byte(content), 0600)
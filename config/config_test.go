@@ -18,6 +18,7 @@ func TestFindAPIKey_VoxConfig(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "") // clear env
 	home := t.TempDir()
 	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	dir := filepath.Join(home, ".vox")
 	os.MkdirAll(dir, 0700)
@@ -29,10 +30,21 @@ func TestFindAPIKey_VoxConfig(t *testing.T) {
 	}
 }
 
+func TestFindAPIKey_IgnoresXDGConfig(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("HOME", t.TempDir())
+	writeXDGConfig(t, "OPENAI_API_KEY=sk-from-xdg\n")
+
+	if got := FindAPIKey(); got != "" {
+		t.Errorf("expected no key from the XDG config, got %q", got)
+	}
+}
+
 func TestFindAPIKey_Bashrc(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "") // clear env
 	home := t.TempDir()
 	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	os.WriteFile(filepath.Join(home, ".bashrc"), []byte(`
 # shell config
@@ -51,6 +63,7 @@ func TestFindAPIKey_NotFound(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "")
 	home := t.TempDir()
 	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	got := FindAPIKey()
 	if got != "" {
@@ -62,6 +75,7 @@ func TestFindAPIKey_EnvTakesPrecedenceOverConfig(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "sk-env-wins")
 	home := t.TempDir()
 	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	dir := filepath.Join(home, ".vox")
 	os.MkdirAll(dir, 0700)
@@ -103,13 +117,15 @@ func TestParseExportFromFile_Variants(t *testing.T) {
 func TestSaveAPIKey(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
-	if err := SaveAPIKey("sk-saved"); err != nil {
+	noKeyring(t)
+	if _, err := SaveAPIKey("sk-saved"); err != nil {
 		t.Fatalf("SaveAPIKey: %v", err)
 	}
 
 	// Read it back.
-	got := keyFromVoxConfig()
+	got, _, _ := keyFromVoxConfig()
 	if got != "sk-saved" {
 		t.Errorf("expected sk-saved, got %q", got)
 	}
@@ -118,12 +134,57 @@ func TestSaveAPIKey(t *testing.T) {
 func TestSaveAPIKey_Overwrites(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
+	noKeyring(t)
 	SaveAPIKey("sk-old")
 	SaveAPIKey("sk-new")
 
-	got := keyFromVoxConfig()
+	got, _, _ := keyFromVoxConfig()
 	if got != "sk-new" {
 		t.Errorf("expected sk-new, got %q", got)
 	}
 }
+
+func TestGet(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if got := Get("history_max_age"); got != "" {
+		t.Errorf("Get with no config = %q, want empty", got)
+	}
+
+	dir := filepath.Join(home, ".vox")
+	os.MkdirAll(dir, 0700)
+	os.WriteFile(filepath.Join(dir, "config"), []byte("OPENAI_API_KEY=sk-x\n# history_max_age=1d\nhistory_max_age=90d\n"), 0600)
+
+	if got := Get("history_max_age"); got != "90d" {
+		t.Errorf("Get(history_max_age) = %q, want 90d", got)
+	}
+	if got := Get("history_max_entries"); got != "" {
+		t.Errorf("Get(history_max_entries) = %q, want empty", got)
+	}
+}
+
+func TestSet(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	noKeyring(t)
+	SaveAPIKey("sk-keep")
+	if err := Set("history_max_age", "30d"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	Set("history_max_age", "90d")
+	if got := Get("history_max_age"); got != "90d" {
+		t.Errorf("after overwrite: %q, want 90d", got)
+	}
+
+	Set("history_max_age", "")
+	data, _ := os.ReadFile(filepath.Join(home, ".vox", "config"))
+	if string(data) != "OPENAI_API_KEY=sk-keep\n" {
+		t.Errorf("after unset, config = %q", data)
+	}
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Values of the credential_store setting, which says where vox login
+// keeps the key.
+const (
+	StoreAuto    = "auto"    // the system keyring if there is one, else the config file
+	StoreKeyring = "keyring" // the system keyring, or fail
+	StoreFile    = "file"    // ~/.vox/config, in plaintext
+)
+
+// ErrNoKeyring is returned when there is no system keyring to use.
+var ErrNoKeyring = errors.New("no system keyring")
+
+// keyringService is the service name vox's keyring items are stored
+// under.
+const keyringService = "vox"
+
+// A keyring is a system credential store holding one secret per account.
+type keyring interface {
+	Name() string
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// systemKeyring returns the system keyring: the Secret Service on Linux,
+// through secret-tool, or the login keychain on macOS, through security.
+// Tests replace it.
+var systemKeyring = func() (keyring, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil, fmt.Errorf("%w: security not found", ErrNoKeyring)
+		}
+		return keychain{}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil, fmt.Errorf("%w: secret-tool not found (install libsecret-tools)", ErrNoKeyring)
+		}
+		return secretService{}, nil
+	}
+	return nil, fmt.Errorf("%w on %s", ErrNoKeyring, runtime.GOOS)
+}
+
+// keyringAccount returns the keyring account holding the key of profile
+// ("" for the top level).
+func keyringAccount(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return "profile/" + profile
+}
+
+// secretService stores secrets with secret-tool, the libsecret client for
+// the D-Bus Secret Service (GNOME Keyring, KWallet and others).
+type secretService struct{}
+
+func (secretService) Name() string { return "the Secret Service keyring" }
+
+func (secretService) Get(account string) (string, error) {
+	out, err := runTool(nil, "secret-tool", "lookup", "service", keyringService, "account", account)
+	// secret-tool lookup exits 1 without a word when there is no such
+	// item. Anything else, such as a locked keyring or no D-Bus session,
+	// is an error.
+	var tool *toolError
+	var exit *exec.ExitError
+	if errors.As(err, &tool) && tool.stderr == "" && errors.As(err, &exit) && exit.ExitCode() == 1 && out == "" {
+		return "", nil
+	}
+	return out, err
+}
+
+func (secretService) Set(account, secret string) error {
+	_, err := runTool(strings.NewReader(secret), "secret-tool", "store", "--label", "vox OpenAI API key ("+account+")", "service", keyringService, "account", account)
+	return err
+}
+
+func (secretService) Delete(account string) error {
+	_, err := runTool(nil, "secret-tool", "clear", "service", keyringService, "account", account)
+	return err
+}
+
+// keychain stores secrets in the macOS login keychain with security.
+type keychain struct{}
+
+func (keychain) Name() string { return "the macOS keychain" }
+
+func (keychain) Get(account string) (string, error) {
+	out, err := runTool(nil, "security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	if err != nil {
+		// Exit status 44: the item doesn't exist.
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 44 {
+			return "", nil
+		}
+	}
+	return out, err
+}
+
+func (keychain) Set(account, secret string) error {
+	// Pass the command on stdin (security -i) so the key isn't in argv,
+	// where any process could see it.
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %q -w %q\n", keyringService, account, "vox OpenAI API key", secret)
+	_, err := runTool(strings.NewReader(cmd), "security", "-i")
+	return err
+}
+
+func (keychain) Delete(account string) error {
+	_, err := runTool(nil, "security", "delete-generic-password", "-s", keyringService, "-a", account)
+	return err
+}
+
+// A toolError is a keyring tool that failed, with what it wrote to
+// stderr.
+type toolError struct {
+	name   string
+	err    error
+	stderr string
+}
+
+func (e *toolError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("%s: %v", e.name, e.err)
+	}
+	return fmt.Sprintf("%s: %v: %s", e.name, e.err, e.stderr)
+}
+
+func (e *toolError) Unwrap() error { return e.err }
+
+// runTool runs a keyring tool and returns its trimmed stdout. A failure
+// is a *toolError.
+func runTool(stdin *strings.Reader, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return strings.TrimSpace(stdout.String()), &toolError{name, err, strings.TrimSpace(stderr.String())}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// keyringKeys caches what the keyring holds for each account, "" for
+// nothing, so one vox run, which looks the key up before recording and
+// again to transcribe, asks the keyring (and maybe prompts to unlock it)
+// once.
+var keyringKeys = map[string]string{}
+
+// keyFromKeyring returns the key kr holds for account, or "".
+func keyFromKeyring(kr keyring, account string) (string, error) {
+	if key, ok := keyringKeys[account]; ok {
+		return key, nil
+	}
+	key, err := kr.Get(account)
+	if err != nil {
+		return "", err
+	}
+	keyringKeys[account] = key
+	return key, nil
+}
+
+// commandKeys caches the output of credential_command, so one vox run
+// doesn't run it (and maybe prompt for a passphrase) twice.
+var commandKeys = map[string]string{}
+
+// keyFromCommand runs command with sh -c and returns the first line it
+// prints. Its stderr and stdin are vox's, so a helper such as pass can
+// prompt.
+func keyFromCommand(command string) (string, error) {
+	if key, ok := commandKeys[command]; ok {
+		return key, nil
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin, cmd.Stderr = os.Stdin, os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("credential_command %q: %w", command, err)
+	}
+	key, _, _ := strings.Cut(string(out), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("credential_command %q printed nothing", command)
+	}
+	commandKeys[command] = key
+	return key, nil
+}
+
+// A Saved reports where SaveAPIKey put the key.
+type Saved struct {
+	Where    string // a config file, or the keyring's name
+	Keyring  bool
+	Fallback error // why the keyring wasn't used, with credential_store=auto
+}
+
+// SaveAPIKey stores the API key for the active profile (or the top
+// level) as credential_store says. In the keyring, the plaintext key is
+// removed from ~/.vox/config and credential_store=keyring is written
+// there, so FindAPIKey knows to look in the keyring. Otherwise the key is
+// written to ~/.vox/config, which has mode 0600.
+func SaveAPIKey(key string) (Saved, error) {
+	profile := Profile()
+	store := Lookup("credential_store").Value
+	var fallback error
+	if store != StoreFile {
+		kr, err := systemKeyring()
+		if err == nil {
+			err = kr.Set(keyringAccount(profile), key)
+		}
+		if err == nil {
+			keyringKeys[keyringAccount(profile)] = key
+			if err := SetIn(profile, "OPENAI_API_KEY", ""); err != nil {
+				return Saved{}, err
+			}
+			if Get("credential_store") != StoreKeyring {
+				if err := SetIn(profile, "credential_store", StoreKeyring); err != nil {
+					return Saved{}, err
+				}
+			}
+			return Saved{Where: kr.Name(), Keyring: true}, nil
+		}
+		if store == StoreKeyring {
+			return Saved{}, err
+		}
+		fallback = err
+	}
+	if err := SetIn(profile, "OPENAI_API_KEY", key); err != nil {
+		return Saved{}, err
+	}
+	return Saved{Where: "~/.vox/config", Fallback: fallback}, nil
+}
+
+// MaskKey shortens key for display, keeping its prefix and last four
+// characters: sk-…WXYZ.
+func MaskKey(key string) string {
+	if len(key) < 12 {
+		return "…"
+	}
+	return key[:3] + "…" + key[len(key)-4:]
+}
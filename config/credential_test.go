@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memKeyring is a keyring in memory.
+type memKeyring map[string]string
+
+func (memKeyring) Name() string { return "the test keyring" }
+
+func (k memKeyring) Get(account string) (string, error) { return k[account], nil }
+
+func (k memKeyring) Set(account, secret string) error {
+	k[account] = secret
+	return nil
+}
+
+func (k memKeyring) Delete(account string) error {
+	delete(k, account)
+	return nil
+}
+
+// useKeyring makes kr the system keyring for the test.
+func useKeyring(t *testing.T, kr keyring) {
+	t.Helper()
+	saved := systemKeyring
+	systemKeyring = func() (keyring, error) { return kr, nil }
+	clear(keyringKeys)
+	t.Cleanup(func() {
+		systemKeyring = saved
+		clear(keyringKeys)
+	})
+}
+
+// noKeyring makes the test run without a system keyring.
+func noKeyring(t *testing.T) {
+	t.Helper()
+	saved := systemKeyring
+	systemKeyring = func() (keyring, error) { return nil, fmt.Errorf("%w: none in tests", ErrNoKeyring) }
+	t.Cleanup(func() { systemKeyring = saved })
+}
+
+func TestSaveAPIKey_Keyring(t *testing.T) {
+	path := writeConfig(t, "OPENAI_API_KEY=sk-plaintext\nlanguage=de\n")
+	writeXDGConfig(t, "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("VOX_PROFILE", "")
+	kr := memKeyring{}
+	useKeyring(t, kr)
+
+	saved, err := SaveAPIKey("sk-in-keyring")
+	if err != nil || !saved.Keyring || saved.Where != "the test keyring" {
+		t.Fatalf("SaveAPIKey = %+v, %v, want it in the keyring", saved, err)
+	}
+	if kr["default"] != "sk-in-keyring" {
+		t.Errorf("keyring = %v", kr)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "language=de\ncredential_store=keyring\n" {
+		t.Errorf("config = %q, want the plaintext key gone", data)
+	}
+	if key, source, _ := LookupAPIKey(); key != "sk-in-keyring" || source != "keyring (default)" {
+		t.Errorf("LookupAPIKey() = %q from %q", key, source)
+	}
+	// The keyring is asked once per run.
+	delete(kr, "default")
+	if key, _, _ := LookupAPIKey(); key != "sk-in-keyring" {
+		t.Errorf("second LookupAPIKey() = %q, want the cached key", key)
+	}
+
+	// A profile's key goes in its own account, and falls back to the
+	// top-level one.
+	useProfile(t, "work")
+	if key, _, _ := LookupAPIKey(); key != "sk-in-keyring" {
+		t.Errorf("profile work without a key: LookupAPIKey() = %q, want the default key", key)
+	}
+	if _, err := SaveAPIKey("sk-work"); err != nil {
+		t.Fatal(err)
+	}
+	if key, source, _ := LookupAPIKey(); key != "sk-work" || source != "keyring (profile/work)" {
+		t.Errorf("profile work: LookupAPIKey() = %q from %q", key, source)
+	}
+	data, _ = os.ReadFile(path)
+	if strings.Contains(string(data), "sk-") {
+		t.Errorf("config = %q, want no keys", data)
+	}
+}
+
+func TestSaveAPIKey_Fallback(t *testing.T) {
+	tests := []struct {
+		store  string
+		err    bool
+		inFile bool
+		reason bool
+	}{
+		{store: "", inFile: true, reason: true},
+		{store: StoreFile, inFile: true},
+		{store: StoreKeyring, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.store, func(t *testing.T) {
+			path := writeConfig(t, "")
+			if tt.store != "" {
+				Set("credential_store", tt.store)
+			}
+			writeXDGConfig(t, "")
+			t.Setenv("VOX_PROFILE", "")
+			noKeyring(t)
+
+			saved, err := SaveAPIKey("sk-file")
+			if (err != nil) != tt.err {
+				t.Fatalf("SaveAPIKey err = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				if !errors.Is(err, ErrNoKeyring) {
+					t.Errorf("err = %v, want ErrNoKeyring", err)
+				}
+				return
+			}
+			data, _ := os.ReadFile(path)
+			if got := strings.Contains(string(data), "OPENAI_API_KEY=sk-file"); got != tt.inFile {
+				t.Errorf("config = %q, want key in file %v", data, tt.inFile)
+			}
+			if (saved.Fallback != nil) != tt.reason {
+				t.Errorf("Fallback = %v, want a reason %v", saved.Fallback, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLookupAPIKey_Command(t *testing.T) {
+	writeConfig(t, "OPENAI_API_KEY=sk-file\ncredential_command=printf 'sk-from-command\\nmore\\n'\n")
+	writeXDGConfig(t, "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("VOX_PROFILE", "")
+
+	if key, source, err := LookupAPIKey(); key != "sk-from-command" || source != "credential_command" || err != nil {
+		t.Errorf("LookupAPIKey() = %q, %q, %v", key, source, err)
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	if key := FindAPIKey(); key != "sk-env" {
+		t.Errorf("with OPENAI_API_KEY: FindAPIKey() = %q, want the env key", key)
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+
+	Set("credential_command", "exit 3")
+	if key, _, err := LookupAPIKey(); key != "" || err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("failing command: LookupAPIKey() = %q, %v, want an error", key, err)
+	}
+	Set("credential_command", "true")
+	if _, _, err := LookupAPIKey(); err == nil || !strings.Contains(err.Error(), "printed nothing") {
+		t.Errorf("silent command: err = %v", err)
+	}
+}
+
+// lockedKeyring is a keyring that can't be read.
+type lockedKeyring struct{ memKeyring }
+
+func (lockedKeyring) Get(string) (string, error) { return "", errors.New("keyring is locked") }
+
+func TestLookupAPIKey_KeyringError(t *testing.T) {
+	writeConfig(t, "credential_store=keyring\nOPENAI_API_KEY=sk-file\n")
+	writeXDGConfig(t, "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("VOX_PROFILE", "")
+	useKeyring(t, lockedKeyring{})
+
+	if key, source, err := LookupAPIKey(); key != "" || source != "keyring (default)" || err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("LookupAPIKey() = %q, %q, %v, want the keyring's error", key, source, err)
+	}
+}
+
+func TestLookupAPIKey_NoKeyring(t *testing.T) {
+	writeConfig(t, "credential_store=keyring\n")
+	writeXDGConfig(t, "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("VOX_PROFILE", "")
+	noKeyring(t)
+
+	if key, source, err := LookupAPIKey(); key != "" || source != "keyring" || !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("LookupAPIKey() = %q, %q, %v, want ErrNoKeyring", key, source, err)
+	}
+}
+
+func TestSecretServiceGet(t *testing.T) {
+	tests := []struct {
+		name, script string
+		want         string
+		err          bool
+	}{
+		{"found", "echo sk-stored", "sk-stored", false},
+		{"no such item", "exit 1", "", false},
+		{"locked", "echo 'Cannot get secret of a locked object' >&2; exit 1", "", true},
+		{"other status", "exit 2", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			os.WriteFile(filepath.Join(bin, "secret-tool"), []byte("#!/bin/sh\n"+tt.script+"\n"), 0o755)
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			got, err := secretService{}.Get("default")
+			if got != tt.want || (err != nil) != tt.err {
+				t.Errorf("Get = %q, %v, want %q and error %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	tests := map[string]string{
+		"sk-abcdefghijklmnop": "sk-…mnop",
+		"sk-short":            "…",
+		"":                    "…",
+	}
+	for key, want := range tests {
+		if got := MaskKey(key); got != want {
+			t.Errorf("MaskKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
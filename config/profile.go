@@ -0,0 +1,113 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Profiles are named sections of a config file that override the
+// top-level settings, so one machine can switch between keys, models and
+// endpoints:
+//
+//	OPENAI_API_KEY=sk-personal
+//
+//	[profile work]
+//	OPENAI_API_KEY=sk-company
+//
+//	[profile local]
+//	base_url=http://localhost:8000/v1
+//
+// A setting missing from the active profile falls back to the top level.
+// The top level always comes first in the file, so older vox versions,
+// which stop at the first OPENAI_API_KEY line, still read the default key.
+
+// profile is the profile selected with UseProfile.
+var profile string
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// UseProfile makes name the active profile, overriding $VOX_PROFILE.
+func UseProfile(name string) {
+	profile = name
+}
+
+// Profile returns the active profile: the one passed to UseProfile, or
+// else $VOX_PROFILE. "" means the top-level settings.
+func Profile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("VOX_PROFILE")
+}
+
+// ValidProfileName reports whether name can be used as a profile name:
+// letters, digits, '.', '_' and '-', starting with a letter or digit.
+func ValidProfileName(name string) bool {
+	return profileName.MatchString(name)
+}
+
+// Profiles returns the names of the profiles in the config files, in
+// file order.
+func Profiles() []string {
+	var names []string
+	for _, path := range configFiles() {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if name, ok := parseSection(scanner.Text()); ok && name != otherSection && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		f.Close()
+	}
+	return names
+}
+
+// otherSection stands for a section header that isn't a profile, so its
+// settings never match.
+const otherSection = "\x00"
+
+// parseSection parses a "[profile name]" header. ok is false if line
+// isn't a section header at all.
+func parseSection(line string) (name string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	inner := strings.TrimSpace(line[1 : len(line)-1])
+	if rest, found := strings.CutPrefix(inner, "profile "); found && strings.TrimSpace(rest) != "" {
+		return strings.TrimSpace(rest), true
+	}
+	return otherSection, true
+}
+
+// sectionBounds returns the range of lines [start, end) holding the
+// settings of section ("" for the top level). found is false if a named
+// section has no header.
+func sectionBounds(lines []string, section string) (start, end int, found bool) {
+	start, end = 0, len(lines)
+	if section != "" {
+		found = false
+		for i, line := range lines {
+			if name, ok := parseSection(line); ok && name == section {
+				start, found = i+1, true
+				break
+			}
+		}
+		if !found {
+			return len(lines), len(lines), false
+		}
+	}
+	for i := start; i < len(lines); i++ {
+		if _, ok := parseSection(lines[i]); ok {
+			return start, i, true
+		}
+	}
+	return start, len(lines), true
+}
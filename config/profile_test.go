@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const profileConfig = `OPENAI_API_KEY=sk-personal
+model=gpt-4o-mini-transcribe
+history_max_age=90d
+
+[profile work]
+OPENAI_API_KEY=sk-company
+model=gpt-4o-transcribe
+
+[profile local]
+base_url=http://localhost:8000/v1
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(home, ".vox")
+	os.MkdirAll(dir, 0700)
+	path := filepath.Join(dir, "config")
+	os.WriteFile(path, []byte(content), 0600)
+	return path
+}
+
+func useProfile(t *testing.T, name string) {
+	t.Helper()
+	UseProfile(name)
+	t.Cleanup(func() { UseProfile("") })
+}
+
+func TestGetProfile(t *testing.T) {
+	writeConfig(t, profileConfig)
+	t.Setenv("VOX_PROFILE", "")
+
+	tests := []struct {
+		profile, key, want string
+	}{
+		{"", "model", "gpt-4o-mini-transcribe"},
+		{"", "base_url", ""},
+		{"work", "model", "gpt-4o-transcribe"},
+		{"work", "history_max_age", "90d"}, // inherited from the top level
+		{"local", "base_url", "http://localhost:8000/v1"},
+		{"local", "model", "gpt-4o-mini-transcribe"},
+	}
+	for _, tt := range tests {
+		useProfile(t, tt.profile)
+		if got := Get(tt.key); got != tt.want {
+			t.Errorf("profile %q: Get(%s) = %q, want %q", tt.profile, tt.key, got, tt.want)
+		}
+	}
+	if got, want := Profiles(), []string{"work", "local"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Profiles() = %v, want %v", got, want)
+	}
+}
+
+func TestFindAPIKey_Profile(t *testing.T) {
+	writeConfig(t, profileConfig)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	t.Setenv("VOX_PROFILE", "work")
+	if got := FindAPIKey(); got != "sk-company" {
+		t.Errorf("VOX_PROFILE=work: got %q, want sk-company", got)
+	}
+	// --profile overrides the environment.
+	useProfile(t, "local")
+	if got := FindAPIKey(); got != "sk-personal" {
+		t.Errorf("profile local (no key): got %q, want the top-level sk-personal", got)
+	}
+	// The environment variable still comes first.
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	useProfile(t, "work")
+	if got := FindAPIKey(); got != "sk-env" {
+		t.Errorf("with env set: got %q, want sk-env", got)
+	}
+}
+
+func TestSetIn(t *testing.T) {
+	path := writeConfig(t, profileConfig)
+
+	SetIn("work", "model", "whisper-1")
+	SetIn("local", "OPENAI_API_KEY", "sk-local")
+	SetIn("", "history_max_entries", "500")
+	SetIn("new", "OPENAI_API_KEY", "sk-new")
+	SetIn("work", "OPENAI_API_KEY", "")
+
+	want := `OPENAI_API_KEY=sk-personal
+model=gpt-4o-mini-transcribe
+history_max_age=90d
+history_max_entries=500
+
+[profile work]
+model=whisper-1
+
+[profile local]
+base_url=http://localhost:8000/v1
+OPENAI_API_KEY=sk-local
+
+[profile new]
+OPENAI_API_KEY=sk-new
+`
+	data, _ := os.ReadFile(path)
+	if string(data) != want {
+		t.Errorf("config:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestSaveAPIKey_Profile(t *testing.T) {
+	path := writeConfig(t, "")
+	useProfile(t, "work")
+
+	noKeyring(t)
+	if _, err := SaveAPIKey("sk-work"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "[profile work]\nOPENAI_API_KEY=sk-work\n" {
+		t.Errorf("config = %q", data)
+	}
+	UseProfile("")
+	if got, _, _ := keyFromVoxConfig(); got != "" {
+		t.Errorf("top-level key = %q, want none", got)
+	}
+}
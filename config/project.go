@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Project config files, looked for in the working directory and each of
+// its parents, in this order within a directory.
+var projectFiles = []string{".vox.toml", ".vox"}
+
+// A Project is a per-project config file: settings a team commits to a
+// repository, such as its vocabulary. Project files are read as
+// untrusted, so only settings marked Project apply; anything else,
+// secrets in particular, is listed in Ignored and never read.
+type Project struct {
+	Path    string
+	Values  map[string]string
+	Ignored []string // keys a project file may not set, in file order
+}
+
+// foundProject is a FindProject result.
+type foundProject struct {
+	p   *Project
+	err error
+}
+
+// projects caches FindProject by working directory, so one vox run looks
+// for and parses the project file once, however many settings it reads.
+var projects = map[string]foundProject{}
+
+// FindProject returns the project file nearest the working directory, or
+// nil if there is none. A .vox that is a directory, like ~/.vox, isn't a
+// project file.
+func FindProject() (*Project, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, nil
+	}
+	if found, ok := projects[dir]; ok {
+		return found.p, found.err
+	}
+	p, err := findProject(dir)
+	projects[dir] = foundProject{p, err}
+	return p, err
+}
+
+// findProject looks for a project file in dir and each of its parents.
+func findProject(dir string) (*Project, error) {
+	for {
+		for _, name := range projectFiles {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return loadProject(path)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// loadProject parses the project file at path.
+func loadProject(path string) (*Project, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parse := parseFlatLine
+	if strings.HasSuffix(path, ".toml") {
+		parse = parseTOMLLine
+	}
+	p := &Project{Path: path, Values: map[string]string{}}
+	scanner := bufio.NewScanner(f)
+	n := 0
+	var pending string // a TOML array spanning lines
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if pending != "" {
+			pending += " " + stripTOMLComment(line)
+			if !strings.HasSuffix(pending, "]") {
+				continue
+			}
+			line, pending = pending, ""
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("%s:%d: sections aren't supported in project files", displayPath(path), n)
+		}
+		key, value, err := parse(line)
+		if errors.Is(err, errOpenArray) {
+			pending = stripTOMLComment(line)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", displayPath(path), n, err)
+		}
+		if s, ok := Known(key); !ok || !s.Project {
+			p.Ignored = append(p.Ignored, key)
+			continue
+		}
+		p.Values[key] = value
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("%s: unterminated array", displayPath(path))
+	}
+	return p, scanner.Err()
+}
+
+// parseFlatLine parses a key=value line, as in ~/.vox/config.
+func parseFlatLine(line string) (key, value string, err error) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", "", fmt.Errorf("want key=value, got %q", line)
+	}
+	return strings.TrimSpace(key), stripQuotes(strings.TrimSpace(value)), nil
+}
+
+// errOpenArray reports a TOML array continued on the next line.
+var errOpenArray = errors.New("array continues")
+
+// parseTOMLLine parses the part of TOML project files need: key = value,
+// where value is a string, number, boolean or array of strings. Arrays
+// become comma-separated lists, as list settings are written in
+// ~/.vox/config.
+func parseTOMLLine(line string) (key, value string, err error) {
+	key, raw, ok := strings.Cut(line, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("want key = value, got %q", line)
+	}
+	value, err = parseTOMLValue(strings.TrimSpace(raw))
+	return key, value, err
+}
+
+// stripTOMLComment removes a # comment that isn't inside a string.
+func stripTOMLComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// parseTOMLValue parses a TOML string, number, boolean or array of
+// strings.
+func parseTOMLValue(raw string) (string, error) {
+	raw = stripTOMLComment(raw)
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	case raw[0] == '"':
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	case raw[0] == '\'':
+		if len(raw) < 2 || raw[len(raw)-1] != '\'' || strings.Contains(raw[1:len(raw)-1], "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw[0] == '[':
+		return parseTOMLArray(raw)
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		return "", fmt.Errorf("unsupported value %s (want a string, number, boolean or array of strings)", raw)
+	}
+	return raw, nil
+}
+
+// parseTOMLArray parses a TOML array of strings into a comma-separated
+// list.
+func parseTOMLArray(raw string) (string, error) {
+	if !strings.HasSuffix(raw, "]") {
+		return "", errOpenArray
+	}
+	var items []string
+	rest := strings.TrimSpace(raw[1 : len(raw)-1])
+	for rest != "" {
+		end := closingQuote(rest)
+		if end < 0 {
+			return "", fmt.Errorf("arrays may only hold strings, got %s", raw)
+		}
+		item, err := parseTOMLValue(rest[:end+1])
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(item, ",") {
+			return "", fmt.Errorf("list items can't contain commas: %q", item)
+		}
+		items = append(items, item)
+		rest = strings.TrimSpace(rest[end+1:])
+		if next, ok := strings.CutPrefix(rest, ","); ok {
+			rest = strings.TrimSpace(next)
+		} else if rest != "" {
+			return "", fmt.Errorf("missing comma in %s", raw)
+		}
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// closingQuote returns the index of the quote closing the string s starts
+// with, or -1 if s doesn't start with a complete string.
+func closingQuote(s string) int {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[0] == '"' && s[i] == '\\':
+			i++
+		case s[i] == s[0]:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeProject writes a project file named name in a new temp dir and
+// returns the dir.
+func writeProject(t *testing.T, name, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	return dir
+}
+
+func TestFindProject(t *testing.T) {
+	writeConfig(t, "")
+	home, _ := os.UserHomeDir()
+
+	// ~/.vox is the user's config directory, not a project file.
+	t.Chdir(home)
+	if p, err := FindProject(); p != nil || err != nil {
+		t.Errorf("in ~: FindProject() = %+v, %v, want none", p, err)
+	}
+
+	root := writeProject(t, ".vox", "language=de\n")
+	os.WriteFile(filepath.Join(root, ".vox.toml"), []byte("language = \"fr\"\n"), 0644)
+	sub := filepath.Join(root, "a", "b")
+	os.MkdirAll(sub, 0755)
+	t.Chdir(sub)
+	p, err := FindProject()
+	if err != nil || p == nil {
+		t.Fatalf("FindProject() = %+v, %v", p, err)
+	}
+	if p.Path != filepath.Join(root, ".vox.toml") || p.Values["language"] != "fr" {
+		t.Errorf("FindProject() = %+v, want .vox.toml in %s with language fr", p, root)
+	}
+
+	os.WriteFile(filepath.Join(root, "a", ".vox"), []byte("language=it\n"), 0644)
+	if p, _ := FindProject(); p == nil || p.Values["language"] != "fr" {
+		t.Errorf("FindProject() = %+v, want the cached .vox.toml, with language fr", p)
+	}
+	clear(projects)
+	if p, _ := FindProject(); p == nil || p.Values["language"] != "it" {
+		t.Errorf("FindProject() = %+v, want the nearest file, with language it", p)
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+		values              map[string]string
+		ignored             []string
+		err                 string
+	}{
+		{
+			name:    "flat",
+			file:    ".vox",
+			content: "# team settings\nlanguage=de\nvocabulary=Isaac Sim, USD\nprompt=\"Robotics standup.\"\n",
+			values:  map[string]string{"language": "de", "vocabulary": "Isaac Sim, USD", "prompt": "Robotics standup."},
+		},
+		{
+			name: "toml",
+			file: ".vox.toml",
+			content: `language = "de"  # German
+prompt = 'Notes on "vox".'
+strip_fillers = true
+chunk_seconds = 120
+vocabulary = [
+  "Isaac Sim", # the simulator
+  'USD',
+]
+replace = ["vox core=>vox-core"]
+`,
+			values:  map[string]string{"language": "de", "prompt": `Notes on "vox".`, "strip_fillers": "true", "vocabulary": "Isaac Sim, USD", "replace": "vox core=>vox-core"},
+			ignored: []string{"chunk_seconds"},
+		},
+		{
+			name:    "secrets and machine settings are ignored",
+			file:    ".vox",
+			content: "OPENAI_API_KEY=sk-evil\nbase_url=https://evil.example\nhistory_path=/tmp/h\nmodel=gpt-4o-transcribe\nchunk_threshold=1\nlanguage=de\nnonsense=1\n",
+			values:  map[string]string{"language": "de"},
+			ignored: []string{"OPENAI_API_KEY", "base_url", "history_path", "model", "chunk_threshold", "nonsense"},
+		},
+		{name: "section", file: ".vox", content: "[profile work]\nlanguage=de\n", err: ".vox:1: sections aren't supported"},
+		{name: "no value", file: ".vox.toml", content: "language\n", err: ".vox.toml:1: want key = value"},
+		{name: "bare word", file: ".vox.toml", content: "language = de\n", err: "unsupported value de"},
+		{name: "comma in item", file: ".vox.toml", content: "vocabulary = [\"a, b\"]\n", err: "can't contain commas"},
+		{name: "numbers in array", file: ".vox.toml", content: "vocabulary = [1, 2]\n", err: "arrays may only hold strings"},
+		{name: "unterminated", file: ".vox.toml", content: "vocabulary = [\n\"a\",\n", err: "unterminated array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeProject(t, tt.file, tt.content)
+			p, err := loadProject(filepath.Join(dir, tt.file))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.Values, tt.values) {
+				t.Errorf("Values = %v, want %v", p.Values, tt.values)
+			}
+			if !reflect.DeepEqual(p.Ignored, tt.ignored) {
+				t.Errorf("Ignored = %v, want %v", p.Ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestLookup_Project(t *testing.T) {
+	writeConfig(t, "OPENAI_API_KEY=sk-user\nlanguage=en\nmodel=whisper-1\n\n[profile work]\nlanguage=fr\n")
+	writeXDGConfig(t, "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("VOX_PROFILE", "")
+	t.Setenv("VOX_LANGUAGE", "")
+	t.Chdir(writeProject(t, ".vox", "OPENAI_API_KEY=sk-project\nlanguage=de\n"))
+
+	if got := Lookup("language"); got.Value != "de" || !strings.HasSuffix(got.Source, "/.vox") {
+		t.Errorf("Lookup(language) = %+v, want de from the project file", got)
+	}
+	if got := Lookup("model"); got.Value != "whisper-1" {
+		t.Errorf("Lookup(model) = %+v, want whisper-1 from ~/.vox/config", got)
+	}
+	useProfile(t, "work")
+	if got := Lookup("language"); got.Value != "de" {
+		t.Errorf("with profile work: Lookup(language) = %+v, want de: the project wins over profiles", got)
+	}
+	t.Setenv("VOX_LANGUAGE", "it")
+	if got := Lookup("language"); got.Value != "it" {
+		t.Errorf("with VOX_LANGUAGE: Lookup(language) = %+v, want it", got)
+	}
+	if got := FindAPIKey(); got != "sk-user" {
+		t.Errorf("FindAPIKey() = %q, want the user's key, never the project's", got)
+	}
+
+	os.WriteFile(".vox", []byte("[x]\n"), 0644)
+	clear(projects)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "sections aren't supported") {
+		t.Errorf("Load with a bad project file: err = %v", err)
+	}
+}
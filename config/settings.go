@@ -0,0 +1,448 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Setting describes one setting vox understands.
+type Setting struct {
+	Key     string
+	Default string // shown by vox config; "" if unset means off or automatic
+	Env     string // environment variable that overrides the files, if any
+	Help    string
+	Project bool // may be set by a project file (see FindProject)
+	check   func(string) error
+}
+
+// Check reports whether value is valid for the setting.
+func (s Setting) Check(value string) error {
+	if s.check == nil || value == "" {
+		return nil
+	}
+	return s.check(value)
+}
+
+// pricePrefix starts the per-model price keys, e.g. price.whisper-1.
+const pricePrefix = "price."
+
+// settings lists the known settings in the order vox config list shows
+// them. The API key isn't one of them: it has its own discovery order
+// (see FindAPIKey) and is set with vox login.
+//
+// Only settings that shape a transcript are Project settings. A project
+// file comes with whatever repository is checked out, so it mustn't be
+// able to send audio or the key elsewhere (base_url), or touch history
+// and budgets, or run a credential_command. Nor may it pick the model or
+// the chunk sizes, which set what a transcript costs: a pricier model,
+// or tiny chunks that each bill a minimum, would spend the user's money.
+var settings = []Setting{
+	{Key: "model", Default: "gpt-4o-mini-transcribe", Env: "VOX_MODEL", Help: "transcription model"},
+	{Key: "base_url", Default: "https://api.openai.com/v1", Env: "VOX_BASE_URL", Help: "API endpoint of an OpenAI-compatible server", check: checkURL},
+	{Key: "language", Env: "VOX_LANGUAGE", Help: "ISO-639-1 language of the audio; empty to auto-detect", Project: true, check: checkLanguage},
+	{Key: "prompt", Env: "VOX_PROMPT", Help: "text sent with the audio to guide transcription, such as names and jargon", Project: true},
+	{Key: "vocabulary", Help: "comma-separated terms to prompt with and to spell exactly so in transcripts", Project: true, check: checkList},
+	{Key: "replace", Help: "comma-separated from=>to rewrites applied to transcripts", Project: true, check: checkReplace},
+	{Key: "strip_fillers", Default: "off", Help: "remove filler words (um, uh, erm) from transcripts", Project: true, check: checkOnOff},
+	{Key: "chunk_seconds", Default: "300", Env: "VOX_CHUNK_SECONDS", Help: "length of each chunk of long audio, in seconds", check: checkPositive},
+	{Key: "chunk_threshold", Default: "480", Env: "VOX_CHUNK_THRESHOLD", Help: "audio longer than this many seconds is sent in chunks", check: checkPositive},
+	{Key: "clipboard", Default: "on", Env: "VOX_CLIPBOARD", Help: "copy transcripts to the clipboard (on or off)", Project: true, check: checkOnOff},
+	{Key: "recorder", Env: "VOX_RECORDER", Help: "capture tool: rec, arecord or parec, or sox for rec writing the WAV; empty to detect", check: oneOf("rec", "arecord", "parec", "sox")},
+	{Key: "credential_store", Default: StoreAuto, Help: "where vox login keeps the key: auto, keyring or file", check: oneOf(StoreAuto, StoreKeyring, StoreFile)},
+	{Key: "credential_command", Help: "shell command printing the API key, such as pass show openai; wins over the keyring and files"},
+	{Key: "history_path", Default: "~/.vox/history.jsonl", Env: "VOX_HISTORY_PATH", Help: "JSONL history file; the key file and SQLite database sit beside it"},
+	{Key: "history_backend", Default: "jsonl", Help: "jsonl or sqlite; switch with vox history migrate", check: oneOf("jsonl", "sqlite")},
+	{Key: "history_encryption", Default: "off", Help: "keyfile, passphrase or off; set by vox history encrypt", check: oneOf("keyfile", "passphrase", "off")},
+	{Key: "history_salt", Help: "passphrase salt, written by vox history encrypt --passphrase"},
+	{Key: "history_max_age", Help: "prune entries older than this (30m, 12h, 90d, 8w)", check: checkAge},
+	{Key: "history_max_entries", Help: "keep only the most recent N entries", check: checkCount},
+	{Key: "budget_daily", Help: "daily spending limit for vox file, in US dollars", check: checkDollars},
+	{Key: "budget_monthly", Help: "monthly spending limit for vox file, in US dollars", check: checkDollars},
+}
+
+// All returns every known setting.
+func All() []Setting {
+	return slices.Clone(settings)
+}
+
+// Known returns the setting named key. price.<model> keys are known for
+// any model.
+func Known(key string) (Setting, bool) {
+	for _, s := range settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	if model, ok := strings.CutPrefix(key, pricePrefix); ok && model != "" {
+		return Setting{Key: key, Help: "price per audio minute of " + model + ", in US dollars", check: checkDollars}, true
+	}
+	return Setting{}, false
+}
+
+// Sources a value can come from, besides a config file.
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+)
+
+// A Value is a setting's effective value and where it came from: "flag
+// --model", "env VOX_MODEL", a config file such as "~/.vox/config" or
+// "~/.vox/config [profile work]", or "default".
+type Value struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// override is a value given on the command line.
+type override struct {
+	value, flag string
+}
+
+var overrides = map[string]override{}
+
+// Override sets key to value for this process, as given by flag. It wins
+// over the environment and the config files.
+func Override(key, value, flag string) {
+	overrides[key] = override{value, flag}
+}
+
+// Lookup returns the effective value of key. In order, it comes from a
+// flag, the setting's environment variable, the project file (for
+// Project settings), the active profile in ~/.vox/config then in
+// $XDG_CONFIG_HOME/vox/config, the top level of those two files, and
+// finally the default.
+func Lookup(key string) Value {
+	if v, ok := lookup(key); ok {
+		return v
+	}
+	s, _ := Known(key)
+	return Value{Key: key, Value: s.Default, Source: SourceDefault}
+}
+
+// Checked is Lookup for a value that must pass its setting's check. The
+// error names the key and where the bad value came from.
+func Checked(key string) (Value, error) {
+	v := Lookup(key)
+	s, _ := Known(key)
+	if err := s.Check(v.Value); err != nil {
+		return v, fmt.Errorf("%s in %s: %w", key, v.Source, err)
+	}
+	return v, nil
+}
+
+// Dollars returns the amount in US dollars key is set to, or 0 if it is
+// unset. An invalid amount is an error, as Checked reports it.
+func Dollars(key string) (float64, error) {
+	v, err := Checked(key)
+	if err != nil || v.Value == "" {
+		return 0, err
+	}
+	return parseDollars(v.Value)
+}
+
+// Age returns the age key is set to, or 0 if it is unset. An invalid age
+// is an error, as Checked reports it.
+func Age(key string) (time.Duration, error) {
+	v, err := Checked(key)
+	if err != nil || v.Value == "" {
+		return 0, err
+	}
+	return ParseAge(v.Value)
+}
+
+// Count returns the whole number key is set to, or 0 if it is unset. An
+// invalid number is an error, as Checked reports it.
+func Count(key string) (int, error) {
+	v, err := Checked(key)
+	if err != nil || v.Value == "" {
+		return 0, err
+	}
+	return strconv.Atoi(v.Value)
+}
+
+// lookup is Lookup without the default.
+func lookup(key string) (Value, bool) {
+	if o, ok := overrides[key]; ok {
+		return Value{key, o.value, SourceFlag + " " + o.flag}, true
+	}
+	if s, ok := Known(key); ok && s.Env != "" {
+		if v := os.Getenv(s.Env); v != "" {
+			return Value{key, v, SourceEnv + " " + s.Env}, true
+		}
+	}
+	if p, err := FindProject(); err == nil && p != nil {
+		if v := p.Values[key]; v != "" {
+			return Value{key, v, displayPath(p.Path)}, true
+		}
+	}
+	files := configFiles()
+	if p := Profile(); p != "" {
+		for _, f := range files {
+			if v := readKeyFromFile(f, p, key); v != "" {
+				return Value{key, v, displayPath(f) + " [profile " + p + "]"}, true
+			}
+		}
+	}
+	for _, f := range files {
+		if v := readKeyFromFile(f, "", key); v != "" {
+			return Value{key, v, displayPath(f)}, true
+		}
+	}
+	return Value{}, false
+}
+
+// FileKeys returns the keys set in the config files, at the top level or
+// in the active profile, in file order without repeats.
+func FileKeys() []string {
+	var keys []string
+	for _, path := range configFiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		section := ""
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if name, ok := parseSection(line); ok {
+				section = name
+				continue
+			}
+			if section != "" && section != Profile() || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if key, _, ok := strings.Cut(line, "="); ok && key != "" && !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// configFiles returns the config files vox reads, highest priority
+// first: ~/.vox/config, which vox writes, then $XDG_CONFIG_HOME/vox/config
+// (~/.config/vox/config by default).
+func configFiles() []string {
+	var files []string
+	if p, err := voxConfigPath(); err == nil {
+		files = append(files, p)
+	}
+	if p := xdgConfigPath(); p != "" {
+		files = append(files, p)
+	}
+	return files
+}
+
+// xdgConfigPath returns $XDG_CONFIG_HOME/vox/config, or "" if there is
+// no config directory.
+func xdgConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vox", configFile)
+}
+
+// displayPath abbreviates the home directory in path to ~.
+func displayPath(path string) string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+			return "~/" + rest
+		}
+	}
+	return path
+}
+
+// ExpandHome replaces a leading ~/ in path with the home directory.
+func ExpandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// Settings are the typed settings for transcribing and recording.
+// History retention, encryption and budgets are read where they're used.
+type Settings struct {
+	Model          string
+	BaseURL        string
+	Language       string // "" to auto-detect
+	Prompt         string
+	Vocabulary     []string
+	Replace        []Replacement
+	StripFillers   bool
+	ChunkSeconds   float64 // length of each chunk of long audio
+	ChunkThreshold float64 // audio longer than this is chunked
+	Clipboard      bool    // copy transcripts to the clipboard
+	Recorder       string  // "" to detect
+	HistoryPath    string  // with ~ expanded
+}
+
+// A Replacement rewrites From, matched as whole words in any case, to To.
+type Replacement struct {
+	From, To string
+}
+
+// Load returns the effective settings. An invalid value, or a project
+// file that can't be parsed, is an error naming where it came from.
+func Load() (Settings, error) {
+	var s Settings
+	var errs []error
+	if _, err := FindProject(); err != nil {
+		errs = append(errs, err)
+	}
+	get := func(key string) string {
+		v, err := Checked(key)
+		if err != nil {
+			errs = append(errs, err)
+			setting, _ := Known(key)
+			return setting.Default
+		}
+		return v.Value
+	}
+	s.Model = get("model")
+	s.BaseURL = strings.TrimSuffix(get("base_url"), "/")
+	s.Language = get("language")
+	s.Prompt = get("prompt")
+	s.Vocabulary = splitList(get("vocabulary"))
+	for _, item := range splitList(get("replace")) {
+		from, to, _ := strings.Cut(item, "=>")
+		s.Replace = append(s.Replace, Replacement{strings.TrimSpace(from), strings.TrimSpace(to)})
+	}
+	s.StripFillers = isOn(get("strip_fillers"))
+	s.ChunkSeconds, _ = strconv.ParseFloat(get("chunk_seconds"), 64)
+	s.ChunkThreshold, _ = strconv.ParseFloat(get("chunk_threshold"), 64)
+	s.Clipboard = isOn(get("clipboard"))
+	s.Recorder = get("recorder")
+	s.HistoryPath = ExpandHome(get("history_path"))
+	if len(errs) > 0 {
+		return s, errs[0]
+	}
+	return s, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func checkList(v string) error {
+	if len(splitList(v)) == 0 {
+		return fmt.Errorf("want a comma-separated list, got %q", v)
+	}
+	return nil
+}
+
+func checkReplace(v string) error {
+	for _, item := range splitList(v) {
+		from, _, ok := strings.Cut(item, "=>")
+		if !ok || strings.TrimSpace(from) == "" {
+			return fmt.Errorf("want from=>to rewrites separated by commas, got %q", item)
+		}
+	}
+	return checkList(v)
+}
+
+func checkURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("want an http or https URL, got %q", v)
+	}
+	return nil
+}
+
+var languageCode = regexp.MustCompile(`^[a-z]{2}$`)
+
+func checkLanguage(v string) error {
+	if !languageCode.MatchString(v) {
+		return fmt.Errorf("want a two-letter ISO-639-1 code such as en or de, got %q", v)
+	}
+	return nil
+}
+
+func checkPositive(v string) error {
+	if n, err := strconv.ParseFloat(v, 64); err != nil || n <= 0 {
+		return fmt.Errorf("want a positive number, got %q", v)
+	}
+	return nil
+}
+
+func checkCount(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 1 {
+		return fmt.Errorf("want a positive whole number, got %q", v)
+	}
+	return nil
+}
+
+func checkDollars(v string) error {
+	_, err := parseDollars(v)
+	return err
+}
+
+// parseDollars parses a non-negative amount in US dollars, with or
+// without a leading $.
+func parseDollars(v string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimPrefix(v, "$"), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("want an amount in US dollars, got %q", v)
+	}
+	return n, nil
+}
+
+func checkAge(v string) error {
+	_, err := ParseAge(v)
+	return err
+}
+
+// ParseAge parses a relative age such as "30m", "12h", "7d" or "2w".
+func ParseAge(s string) (time.Duration, error) {
+	if len(s) >= 2 {
+		unit := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && unit != 0 && n >= 0 {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid age %q (want e.g. 30m, 12h, 90d or 8w)", s)
+}
+
+func checkOnOff(v string) error {
+	switch strings.ToLower(v) {
+	case "on", "off", "true", "false", "yes", "no", "1", "0":
+		return nil
+	}
+	return fmt.Errorf("want on or off, got %q", v)
+}
+
+// isOn reports whether an on/off value is on.
+func isOn(v string) bool {
+	switch strings.ToLower(v) {
+	case "on", "true", "yes", "1":
+		return true
+	}
+	return false
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		if !slices.Contains(values, v) {
+			return fmt.Errorf("want %s, got %q", strings.Join(values, ", "), v)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeXDGConfig writes $XDG_CONFIG_HOME/vox/config under a new temp dir.
+func writeXDGConfig(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "vox"), 0700)
+	os.WriteFile(filepath.Join(dir, "vox", "config"), []byte(content), 0600)
+}
+
+func TestLookup(t *testing.T) {
+	writeConfig(t, "model=from-vox\n\n[profile work]\nlanguage=fr\n")
+	writeXDGConfig(t, "model=from-xdg\nchunk_seconds=120\nlanguage=de\n\n[profile work]\nchunk_seconds=60\n")
+	t.Chdir(t.TempDir())
+	t.Setenv("VOX_PROFILE", "")
+	t.Setenv("VOX_MODEL", "")
+	t.Setenv("VOX_LANGUAGE", "")
+	t.Setenv("VOX_CHUNK_SECONDS", "")
+
+	check := func(key, value, source string) {
+		t.Helper()
+		got := Lookup(key)
+		if got.Value != value || !strings.HasSuffix(got.Source, source) {
+			t.Errorf("Lookup(%s) = %q from %q, want %q from ...%q", key, got.Value, got.Source, value, source)
+		}
+	}
+	check("model", "from-vox", ".vox/config")
+	check("chunk_seconds", "120", "vox/config")
+	check("chunk_threshold", "480", SourceDefault)
+	check("language", "de", "vox/config")
+
+	useProfile(t, "work")
+	check("language", "fr", ".vox/config [profile work]")
+	check("chunk_seconds", "60", "vox/config [profile work]")
+	check("model", "from-vox", ".vox/config")
+
+	t.Setenv("VOX_MODEL", "from-env")
+	check("model", "from-env", "env VOX_MODEL")
+	Override("model", "from-flag", "--model")
+	t.Cleanup(func() { delete(overrides, "model") })
+	check("model", "from-flag", "flag --model")
+	if got := Get("budget_daily"); got != "" {
+		t.Errorf("Get(budget_daily) = %q, want empty (no default)", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	writeConfig(t, "clipboard=off\nchunk_threshold=600\nhistory_path=~/notes/vox.jsonl\nreplace=vox core=>vox-core, teh => the\n")
+	writeXDGConfig(t, "")
+	t.Chdir(t.TempDir())
+	for _, env := range []string{"VOX_PROFILE", "VOX_MODEL", "VOX_BASE_URL", "VOX_LANGUAGE", "VOX_PROMPT", "VOX_CHUNK_SECONDS", "VOX_CHUNK_THRESHOLD", "VOX_CLIPBOARD", "VOX_RECORDER", "VOX_HISTORY_PATH"} {
+		t.Setenv(env, "")
+	}
+	home, _ := os.UserHomeDir()
+
+	got, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := Settings{
+		Model:          "gpt-4o-mini-transcribe",
+		BaseURL:        "https://api.openai.com/v1",
+		ChunkSeconds:   300,
+		ChunkThreshold: 600,
+		Clipboard:      false,
+		Replace:        []Replacement{{"vox core", "vox-core"}, {"teh", "the"}},
+		HistoryPath:    filepath.Join(home, "notes", "vox.jsonl"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	t.Setenv("VOX_CHUNK_SECONDS", "-5")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "chunk_seconds in env VOX_CHUNK_SECONDS") {
+		t.Errorf("Load with bad env: err = %v, want it to name chunk_seconds and its source", err)
+	}
+}
+
+func TestKnown(t *testing.T) {
+	tests := []struct {
+		key, value string
+		known, ok  bool
+	}{
+		{"model", "anything", true, true},
+		{"recorder", "arecord", true, true},
+		{"recorder", "ffmpeg", true, false},
+		{"base_url", "localhost:8000", true, false},
+		{"history_max_age", "90d", true, true},
+		{"history_max_age", "3 months", true, false},
+		{"price.whisper-1", "0.006", true, true},
+		{"price.whisper-1", "free", true, false},
+		{"price.", "1", false, false},
+		{"replace", "teh=>the, vox core => vox-core", true, true},
+		{"replace", "teh->the", true, false},
+		{"vocabulary", " , ", true, false},
+		{"OPENAI_API_KEY", "sk-x", false, false},
+	}
+	for _, tt := range tests {
+		s, known := Known(tt.key)
+		if known != tt.known {
+			t.Errorf("Known(%q) = %v, want %v", tt.key, known, tt.known)
+			continue
+		}
+		if known {
+			if err := s.Check(tt.value); (err == nil) != tt.ok {
+				t.Errorf("%s.Check(%q) = %v, want ok %v", tt.key, tt.value, err, tt.ok)
+			}
+		}
+	}
+}
+
+func TestFileKeys(t *testing.T) {
+	writeConfig(t, "OPENAI_API_KEY=sk-x\n# model=x\nprice.whisper-1=0.01\n\n[profile work]\nprice.gpt-4o-transcribe=0.02\n")
+	writeXDGConfig(t, "language=de\nprice.whisper-1=0.02\n")
+	t.Setenv("VOX_PROFILE", "")
+
+	want := []string{"OPENAI_API_KEY", "price.whisper-1", "language"}
+	if got := FileKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FileKeys() = %v, want %v", got, want)
+	}
+	useProfile(t, "work")
+	want = []string{"OPENAI_API_KEY", "price.whisper-1", "price.gpt-4o-transcribe", "language"}
+	if got := FileKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("with profile work: FileKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestTypedSettings(t *testing.T) {
+	writeConfig(t, "history_max_age=90d\nbudget_daily=$1.50\n")
+	writeXDGConfig(t, "history_max_entries=0\n")
+	t.Chdir(t.TempDir())
+	t.Setenv("VOX_PROFILE", "")
+
+	if age, err := Age("history_max_age"); age != 90*24*time.Hour || err != nil {
+		t.Errorf("Age = %v, %v", age, err)
+	}
+	if d, err := Dollars("budget_daily"); d != 1.5 || err != nil {
+		t.Errorf("Dollars = %v, %v", d, err)
+	}
+	if d, err := Dollars("budget_monthly"); d != 0 || err != nil {
+		t.Errorf("Dollars(unset) = %v, %v", d, err)
+	}
+	// The error names where the bad value is.
+	want := "history_max_entries in " + filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "vox", "config")
+	if _, err := Count("history_max_entries"); err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Count: err = %v, want it to start %q", err, want)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"90d", 90 * 24 * time.Hour, false},
+		{"8w", 8 * 7 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"7", 0, true},
+		{"7x", 0, true},
+		{"-1d", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Backend stores history entries. Store, a JSONL file, is the default;
+// SQLite keeps the same entries in a database with a full-text index.
+// Entries are kept in append order, oldest first, and every backend
+// round-trips them unchanged.
+type Backend interface {
+	// Append adds an entry, then prunes per the retention policy.
+	Append(e Entry) error
+	// List returns up to n entries (all if n == 0), most recent first.
+	List(n int) ([]Entry, error)
+	// Skipped returns the unreadable records the last List or Search
+	// passed over.
+	Skipped() []BadLine
+	// ReadAll returns every entry oldest first, reporting unreadable
+	// records rather than failing on them.
+	ReadAll() ([]Entry, []BadLine, error)
+	// Search returns entries matching q, most recent first, at most
+	// limit of them if limit > 0.
+	Search(q Query, limit int) ([]Hit, error)
+	// Rewrite atomically replaces every entry with fn's result.
+	Rewrite(fn func([]Entry) ([]Entry, error)) error
+	// Update applies fn to every entry with the given ID and returns how
+	// many there were: entries with the same timestamp and text share an
+	// ID. It returns ErrNotFound if no entry has that ID.
+	Update(id string, fn func(*Entry)) (int, error)
+	// Merge adds the incoming entries not already present.
+	Merge(incoming []Entry) (added, skipped int, err error)
+	// SetRetention sets the policy Append and Prune enforce.
+	SetRetention(r Retention)
+	// Prune removes entries the retention policy no longer keeps.
+	Prune(now time.Time) (int, error)
+	// Clear removes every entry.
+	Clear() error
+}
+
+// Hit is one search result.
+type Hit struct {
+	Entry Entry
+	Pos   int     // 1-based position in List order, as shown by vox ls
+	Locs  [][]int // byte offsets of text matches in Entry.Text
+}
+
+// search implements Search over entries in List order.
+func search(entries []Entry, q Query, limit int) ([]Hit, error) {
+	m, err := q.Compile()
+	if err != nil {
+		return nil, err
+	}
+	hits := []Hit{}
+	for i, e := range entries {
+		locs, ok := m.Match(e)
+		if !ok {
+			continue
+		}
+		hits = append(hits, Hit{Entry: e, Pos: i + 1, Locs: locs})
+		if limit > 0 && len(hits) == limit {
+			break
+		}
+	}
+	return hits, nil
+}
+
+// prune implements Prune for any backend: one read to check, and a
+// rewrite only if r drops something.
+func prune(b Backend, r Retention, now time.Time) (int, error) {
+	if r.IsZero() {
+		return 0, nil
+	}
+	entries, _, err := b.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(r.Apply(entries, now)) == len(entries) {
+		return 0, nil
+	}
+
+	removed := 0
+	err = b.Rewrite(func(all []Entry) ([]Entry, error) {
+		kept := r.Apply(all, now)
+		removed = len(all) - len(kept)
+		return kept, nil
+	})
+	return removed, err
+}
+
+// update implements Update for any backend with a full rewrite.
+func update(b Backend, id string, fn func(*Entry)) (int, error) {
+	n := 0
+	err := b.Rewrite(func(all []Entry) ([]Entry, error) {
+		n = 0
+		for i := range all {
+			if all[i].ID == id {
+				fn(&all[i])
+				n++
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return all, nil
+	})
+	return n, err
+}
+
+// errNothingToMerge stops the merge rewrite when every incoming entry is
+// already present, so the history is left untouched.
+var errNothingToMerge = errors.New("nothing to merge")
+
+// merge implements Merge for any backend with a full rewrite, or none if
+// nothing is added.
+func merge(b Backend, incoming []Entry) (added, skipped int, err error) {
+	err = b.Rewrite(func(all []Entry) ([]Entry, error) {
+		added, skipped = 0, 0
+		seen := make(map[string]bool, len(all))
+		for _, e := range all {
+			seen[e.Timestamp+"\x00"+e.Text] = true
+		}
+		for _, e := range incoming {
+			key := e.Timestamp + "\x00" + e.Text
+			if seen[key] {
+				skipped++
+				continue
+			}
+			seen[key] = true
+			all = append(all, e)
+			added++
+		}
+		if added == 0 {
+			return nil, errNothingToMerge
+		}
+		sort.SliceStable(all, func(i, j int) bool {
+			return entryBefore(all[i], all[j])
+		})
+		return all, nil
+	})
+	if errors.Is(err, errNothingToMerge) {
+		err = nil
+	}
+	return added, skipped, err
+}
+
+// entryBefore orders entries by timestamp. Unparseable timestamps compare
+// as strings so the order is still deterministic.
+func entryBefore(a, b Entry) bool {
+	ta, errA := a.Time()
+	tb, errB := b.Time()
+	if errA != nil || errB != nil {
+		return a.Timestamp < b.Timestamp
+	}
+	return ta.Before(tb)
+}
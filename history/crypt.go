@@ -0,0 +1,272 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encrypted history lines have the form
+//
+//	enc1:<salt>:<nonce+ciphertext>
+//
+// with both parts in unpadded standard base64. The ciphertext is the
+// ordinary JSON line sealed with AES-256-GCM. The salt is empty for lines
+// sealed with a key file; for lines sealed with a passphrase it is the
+// PBKDF2 salt, so a history copied to another machine can be read with
+// the passphrase alone. Lines stay independent, which keeps appends
+// append-only and a damaged line from affecting its neighbours.
+const encPrefix = "enc1:"
+
+// KeySize is the length in bytes of a history key.
+const KeySize = 32
+
+// pbkdf2Iterations is the PBKDF2-SHA256 work factor for passphrases. A
+// variable so tests can make derivation cheap.
+var pbkdf2Iterations = 600_000
+
+var (
+	// ErrEncrypted is returned when the history holds encrypted lines
+	// and the store has no cipher to open them.
+	ErrEncrypted = errors.New("history is encrypted and no key is configured")
+
+	// ErrWrongKey is returned when the store's cipher opens none of the
+	// encrypted lines in the history.
+	ErrWrongKey = errors.New("history can't be decrypted with this key or passphrase")
+
+	// errDecrypt marks a single encrypted line that didn't open.
+	errDecrypt = errors.New("can't decrypt line")
+)
+
+// Cipher seals and opens encrypted history lines, with either a random
+// key (held in a key file) or a key derived from a passphrase.
+type Cipher struct {
+	key        []byte // key-file mode
+	passphrase string // passphrase mode
+	salt       []byte // passphrase mode: salt for newly sealed lines
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD // by encoded salt
+}
+
+// NewKeyCipher returns a Cipher for a KeySize-byte key.
+func NewKeyCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("history key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return &Cipher{key: key}, nil
+}
+
+// NewPassphraseCipher returns a Cipher that derives its keys from
+// passphrase. New lines are sealed with a key derived with salt; lines
+// sealed under other salts are opened by deriving their key in turn.
+func NewPassphraseCipher(passphrase string, salt []byte) (*Cipher, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	if len(salt) < 16 {
+		return nil, fmt.Errorf("salt must be at least 16 bytes, got %d", len(salt))
+	}
+	return &Cipher{passphrase: passphrase, salt: salt}, nil
+}
+
+// RandomBytes returns n bytes from the system's secure random source, for
+// new keys (KeySize) and salts.
+func RandomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// aead returns the AES-GCM instance for lines with the given encoded salt.
+func (c *Cipher) aead(salt string) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a, ok := c.aeads[salt]; ok {
+		return a, nil
+	}
+
+	var key []byte
+	switch {
+	case c.key != nil && salt == "":
+		key = c.key
+	case c.passphrase != "" && salt != "":
+		raw, err := base64.RawStdEncoding.DecodeString(salt)
+		if err != nil {
+			return nil, errDecrypt
+		}
+		if key, err = pbkdf2.Key(sha256.New, c.passphrase, raw, pbkdf2Iterations, KeySize); err != nil {
+			return nil, err
+		}
+	default:
+		// Sealed in the other mode: this cipher can't have the key.
+		return nil, errDecrypt
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	a, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if c.aeads == nil {
+		c.aeads = map[string]cipher.AEAD{}
+	}
+	c.aeads[salt] = a
+	return a, nil
+}
+
+// seal encrypts a JSON line (without its newline) into an encrypted line.
+func (c *Cipher) seal(plain []byte) ([]byte, error) {
+	salt := ""
+	if c.passphrase != "" {
+		salt = base64.RawStdEncoding.EncodeToString(c.salt)
+	}
+	a, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := RandomBytes(a.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	sealed := a.Seal(nonce, nonce, plain, []byte(encPrefix))
+
+	line := make([]byte, 0, len(encPrefix)+len(salt)+1+base64.RawStdEncoding.EncodedLen(len(sealed)))
+	line = append(line, encPrefix...)
+	line = append(line, salt...)
+	line = append(line, ':')
+	return base64.RawStdEncoding.AppendEncode(line, sealed), nil
+}
+
+// open decrypts an encrypted line back to its JSON.
+func (c *Cipher) open(line []byte) ([]byte, error) {
+	rest := bytes.TrimPrefix(bytes.TrimSpace(line), []byte(encPrefix))
+	salt, data, ok := bytes.Cut(rest, []byte(":"))
+	if !ok {
+		return nil, errDecrypt
+	}
+	a, err := c.aead(string(salt))
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(string(data))
+	if err != nil || len(sealed) < a.NonceSize() {
+		return nil, errDecrypt
+	}
+	nonce, ciphertext := sealed[:a.NonceSize()], sealed[a.NonceSize():]
+	plain, err := a.Open(nil, nonce, ciphertext, []byte(encPrefix))
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return plain, nil
+}
+
+// isEncrypted reports whether line is an encrypted history line.
+func isEncrypted(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(line), []byte(encPrefix))
+}
+
+// SetCipher makes the store open encrypted lines with c and seal every
+// line it writes from now on. A nil c turns encryption off.
+func (s *Store) SetCipher(c *Cipher) {
+	s.cipher = c
+	s.sealer = c
+}
+
+// Cipher returns the cipher the store opens encrypted lines with, or nil.
+func (s *Store) Cipher() *Cipher {
+	return s.cipher
+}
+
+// Encrypted reports whether any line in the history is encrypted.
+func (s *Store) Encrypted() (bool, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if isEncrypted(line) {
+			return true, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// Encrypt rewrites the whole history sealed with c and makes the store
+// use c from then on. Lines already encrypted are opened with the
+// store's current cipher, or with c if it has none.
+func (s *Store) Encrypt(c *Cipher) error {
+	old := s.cipher
+	if s.cipher == nil {
+		s.cipher = c
+	}
+	s.sealer = c
+	if err := s.Rewrite(keepAll); err != nil {
+		s.SetCipher(old)
+		return err
+	}
+	s.SetCipher(c)
+	return nil
+}
+
+// Decrypt rewrites the whole history as plaintext, opening encrypted
+// lines with the store's cipher. Later writes are plaintext too. If an
+// encrypted line won't open, Decrypt fails and changes nothing: left in a
+// plaintext history, it would make the whole file unreadable without a
+// key.
+func (s *Store) Decrypt() error {
+	_, bad, err := s.ReadAll()
+	if err != nil {
+		return err
+	}
+	var sealed []string
+	for _, b := range bad {
+		if isEncrypted(b.raw) {
+			sealed = append(sealed, strconv.Itoa(b.Line))
+		}
+	}
+	switch {
+	case len(sealed) == 1:
+		return fmt.Errorf("encrypted history line %s won't open with this key; fix or delete it before decrypting", sealed[0])
+	case len(sealed) > 1:
+		return fmt.Errorf("encrypted history lines %s won't open with this key; fix or delete them before decrypting", strings.Join(sealed, ", "))
+	}
+	sealer := s.sealer
+	s.sealer = nil
+	if err := s.Rewrite(keepAll); err != nil {
+		s.sealer = sealer
+		return err
+	}
+	return nil
+}
+
+func keepAll(all []Entry) ([]Entry, error) { return all, nil }
@@ -0,0 +1,189 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Passphrase tests don't need a real work factor.
+	pbkdf2Iterations = 1000
+}
+
+func testKeyCipher(t *testing.T) *Cipher {
+	t.Helper()
+	key, err := RandomBytes(KeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewKeyCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testPassphraseCipher(t *testing.T, pass string) *Cipher {
+	t.Helper()
+	salt, err := RandomBytes(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewPassphraseCipher(pass, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		cipher func(*testing.T) *Cipher
+	}{
+		{"key file", testKeyCipher},
+		{"passphrase", func(t *testing.T) *Cipher { return testPassphraseCipher(t, "correct horse") }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history.jsonl")
+			store := NewStore(path)
+			store.SetCipher(tt.cipher(t))
+			store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "unreleased gantry redesign", Tags: []string{"secret"}})
+			store.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "second"})
+
+			data, _ := os.ReadFile(path)
+			if bytes.Contains(data, []byte("gantry")) || bytes.Contains(data, []byte(`"ts"`)) {
+				t.Fatalf("plaintext on disk:\n%s", data)
+			}
+			if !strings.HasPrefix(string(data), encPrefix) {
+				t.Fatalf("line doesn't start with %q: %s", encPrefix, data)
+			}
+
+			all, bad, err := store.ReadAll()
+			if err != nil || len(bad) != 0 {
+				t.Fatalf("ReadAll: %v, bad %v", err, bad)
+			}
+			if len(all) != 2 || all[0].Text != "unreleased gantry redesign" || !all[0].HasTag("secret") {
+				t.Errorf("ReadAll = %+v", all)
+			}
+			last, err := store.List(1)
+			if err != nil || len(last) != 1 || last[0].Text != "second" {
+				t.Errorf("List(1) = %+v, %v", last, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.SetCipher(testKeyCipher(t))
+	store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "one"})
+	store.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "two"})
+
+	tests := []struct {
+		name   string
+		cipher *Cipher
+		want   error
+	}{
+		{"no cipher", nil, ErrEncrypted},
+		{"other key", testKeyCipher(t), ErrWrongKey},
+		{"passphrase for key-file lines", testPassphraseCipher(t, "x"), ErrWrongKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(path)
+			s.SetCipher(tt.cipher)
+			if _, _, err := s.ReadAll(); !errors.Is(err, tt.want) {
+				t.Errorf("ReadAll: err = %v, want %v", err, tt.want)
+			}
+			if _, err := s.List(1); !errors.Is(err, tt.want) {
+				t.Errorf("List(1): err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptedDamagedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.SetCipher(testKeyCipher(t))
+	store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "one"})
+
+	// A crash mid-append leaves a truncated encrypted line.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	f.WriteString(encPrefix + ":AAAA")
+	f.Close()
+
+	all, bad, err := store.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(all) != 1 || len(bad) != 1 || bad[0].Line != 2 {
+		t.Errorf("got %d entries, bad %v; want 1 entry and line 2 bad", len(all), bad)
+	}
+	if last, err := store.List(1); err != nil || len(last) != 1 || last[0].Text != "one" {
+		t.Errorf("List(1) = %+v, %v", last, err)
+	}
+
+	// Decrypting would leave the line that won't open in a plaintext
+	// history no reader could get past, so it fails and changes nothing.
+	before, _ := os.ReadFile(path)
+	if err := store.Decrypt(); err == nil || !strings.Contains(err.Error(), "line 2 won't open") {
+		t.Fatalf("Decrypt: err = %v, want line 2 named", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Errorf("Decrypt changed the file:\n%s", after)
+	}
+	store.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "two"})
+	if all, _, err := store.ReadAll(); err != nil || len(all) != 2 {
+		t.Errorf("after a failed Decrypt: %d entries, %v; want both, still encrypted", len(all), err)
+	}
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte(`"text":"two"`)) {
+		t.Errorf("append after a failed Decrypt wrote plaintext:\n%s", data)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "plain before"})
+
+	if enc, _ := store.Encrypted(); enc {
+		t.Fatal("plaintext history reported as encrypted")
+	}
+	c := testPassphraseCipher(t, "hunter2")
+	if err := store.Encrypt(c); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	store.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "sealed after"})
+	if enc, _ := store.Encrypted(); !enc {
+		t.Fatal("history not encrypted after Encrypt")
+	}
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("plain before")) || bytes.Contains(data, []byte("sealed after")) {
+		t.Fatalf("plaintext on disk after Encrypt:\n%s", data)
+	}
+
+	// Another machine with the same passphrase but its own salt can read it.
+	other := NewStore(path)
+	other.SetCipher(testPassphraseCipher(t, "hunter2"))
+	if all, _, err := other.ReadAll(); err != nil || len(all) != 2 {
+		t.Fatalf("read with same passphrase: %d entries, %v", len(all), err)
+	}
+
+	if err := store.Decrypt(); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if isEncrypted(data) || !bytes.Contains(data, []byte("sealed after")) {
+		t.Fatalf("history not plaintext after Decrypt:\n%s", data)
+	}
+	if all, _, err := NewStore(path).ReadAll(); err != nil || len(all) != 2 {
+		t.Errorf("read without cipher after Decrypt: %d entries, %v", len(all), err)
+	}
+}
@@ -3,22 +3,80 @@ package history
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Entry represents a single transcription record.
+//
+// ID is a stable short identifier. Lines written before IDs existed have
+// none on disk; List derives the same ID for them that Append would have
+// assigned, so an entry's ID never depends on its position in the file.
+//
+// Fields after ts, text and duration_s are optional and omitted when
+// empty, so lines without them keep the original schema and older
+// readers, which ignore unknown keys, can still parse every line.
 type Entry struct {
-	Timestamp string  `json:"ts"`
-	Text      string  `json:"text"`
-	DurationS float64 `json:"duration_s"`
+	Timestamp string   `json:"ts"`
+	Text      string   `json:"text"`
+	DurationS float64  `json:"duration_s"`
+	ID        string   `json:"id,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Note      string   `json:"note,omitempty"`
+
+	// Where the audio came from and how it was transcribed.
+	Source   string  `json:"source,omitempty"`   // SourceMic, SourceFile or SourceStdin
+	File     string  `json:"file,omitempty"`     // absolute path, for SourceFile
+	Provider string  `json:"provider,omitempty"` // e.g. "openai"
+	Model    string  `json:"model,omitempty"`
+	Language string  `json:"language,omitempty"` // as reported by the provider
+	Chunks   int     `json:"chunks,omitempty"`   // API requests the audio was split into
+	CostUSD  float64 `json:"cost_usd,omitempty"` // estimated, in US dollars
+}
+
+// Entry sources.
+const (
+	SourceMic   = "mic"
+	SourceFile  = "file"
+	SourceStdin = "stdin"
+)
+
+// ErrNotFound is returned by Update when no entry has the given ID.
+var ErrNotFound = errors.New("entry not found")
+
+// idLength is the number of hex digits in an entry ID.
+const idLength = 8
+
+// DeriveID returns the ID for an entry with the given timestamp and text:
+// a truncated SHA-256 of both.
+func DeriveID(ts, text string) string {
+	sum := sha256.Sum256([]byte(ts + "\x00" + text))
+	return hex.EncodeToString(sum[:])[:idLength]
+}
+
+// Time parses the entry's RFC3339 timestamp.
+func (e Entry) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339, e.Timestamp)
 }
 
 // Store manages reading and writing history entries to a JSONL file.
 type Store struct {
-	path string
+	path      string
+	retention Retention
+	cipher    *Cipher // opens encrypted lines
+	sealer    *Cipher // seals written lines; nil writes plaintext
+	skipped   []BadLine
 }
 
 // NewStore creates a Store that reads and writes to the given file path.
@@ -36,65 +94,463 @@ func DefaultPath() string {
 }
 
 // Append adds an entry to the history file. It creates the parent directory
-// and file if they do not exist.
+// and file if they do not exist. If the store has a retention policy and
+// something has expired, entries it no longer keeps are pruned afterwards.
 func (s *Store) Append(e Entry) error {
-	dir := filepath.Dir(s.path)
-	if err := os.MkdirAll(dir, 0o700); err != nil {
+	if err := s.append(e); err != nil {
+		return err
+	}
+	if s.retention.IsZero() {
+		return nil
+	}
+	now := time.Now()
+	expired, err := s.expired(now)
+	if err == nil && expired {
+		_, err = s.Prune(now)
+	}
+	if err != nil {
+		return fmt.Errorf("pruning history: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) append(e Entry) error {
+	unlock, err := s.lock()
+	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	return json.NewEncoder(f).Encode(e)
+	// Terminate a partial last line (from a crash mid-write) so it doesn't
+	// swallow this entry too.
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				return err
+			}
+		}
+	}
+
+	line, err := s.encodeLine(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	return err
+}
+
+// encodeLine returns e as a newline-terminated history line, assigning
+// its ID and sealing it if the store encrypts.
+func (s *Store) encodeLine(e Entry) ([]byte, error) {
+	if e.ID == "" {
+		e.ID = DeriveID(e.Timestamp, e.Text)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	if s.sealer != nil {
+		if line, err = s.sealer.seal(line); err != nil {
+			return nil, err
+		}
+	}
+	return append(line, '\n'), nil
 }
 
 // List returns history entries in reverse chronological order (most recent first).
 // If n > 0, at most n entries are returned. If n == 0, all entries are returned.
 // If the history file does not exist, an empty slice and nil error are returned.
+// Malformed lines are skipped; Skipped reports them.
+//
+// With n > 0 the file is read backwards from the end, so listing recent
+// entries stays fast however large the history grows.
 func (s *Store) List(n int) ([]Entry, error) {
+	s.skipped = nil
+	if n > 0 {
+		return s.readLast(n)
+	}
+
+	entries, bad, err := s.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	s.skipped = bad
+
+	// Reverse to most-recent-first order.
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, nil
+}
+
+// Skipped returns the malformed lines the last List or Search skipped.
+// Lines skipped while listing the most recent entries have no number.
+func (s *Store) Skipped() []BadLine {
+	return s.skipped
+}
+
+// BadLine describes a history line that could not be parsed, e.g. one left
+// partial by a crash or damaged by hand-editing.
+type BadLine struct {
+	Line int // 1-based line number (row seq for SQLite); 0 if unknown
+	Err  error
+
+	raw   []byte // the line as read, which Rewrite writes back unchanged
+	after string // ID of the entry before it; "" at the start
+}
+
+func (b BadLine) Error() string {
+	if b.Line == 0 {
+		return b.Err.Error()
+	}
+	return fmt.Sprintf("line %d: %v", b.Line, b.Err)
+}
+
+// ReadAll returns every entry in file order (oldest first). Malformed
+// lines are skipped and reported in bad rather than failing the read.
+// A missing file yields no entries and no error.
+func (s *Store) ReadAll() (entries []Entry, bad []BadLine, err error) {
+	entries, bad, _, err = s.readAll()
+	return entries, bad, err
+}
+
+// readAll is ReadAll that also returns the size of the file it read.
+func (s *Store) readAll() ([]Entry, []BadLine, int64, error) {
 	f, err := os.Open(s.path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return []Entry{}, nil
+			return []Entry{}, nil, 0, nil
 		}
-		return nil, err
+		return nil, nil, 0, err
 	}
 	defer f.Close()
 
-	var entries []Entry
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		var e Entry
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			return nil, err
+	// Stat before reading: an append racing with the read can only make
+	// the recorded size too small, which Rewrite treats as a change.
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	entries := []Entry{}
+	var bad []BadLine
+	after := ""
+	opened, unopened := 0, 0
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		// ReadBytes rather than a Scanner: long dictations exceed its line limit.
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			e, perr := s.parseLine(line)
+			switch {
+			case errors.Is(perr, ErrEncrypted):
+				return nil, nil, 0, perr
+			case errors.Is(perr, errDecrypt):
+				unopened++
+			case perr == nil && isEncrypted(line):
+				opened++
+			}
+			if perr != nil {
+				raw := bytes.TrimRight(line, "\r\n")
+				bad = append(bad, BadLine{Line: n, Err: perr, raw: raw, after: after})
+			} else {
+				entries = append(entries, e)
+				after = e.ID
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
 		}
-		entries = append(entries, e)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	// One line that won't open is damage; none opening is the wrong key.
+	if unopened > 0 && opened == 0 {
+		return nil, nil, 0, ErrWrongKey
+	}
+	return entries, bad, info.Size(), nil
+}
+
+// parseLine decodes one history line, decrypting it first if it is
+// encrypted and deriving the ID if it has none.
+func (s *Store) parseLine(line []byte) (Entry, error) {
+	if isEncrypted(line) {
+		if s.cipher == nil {
+			return Entry{}, ErrEncrypted
+		}
+		plain, err := s.cipher.open(line)
+		if err != nil {
+			return Entry{}, err
+		}
+		line = plain
+	}
+	return decodeLine(line)
+}
+
+// decodeLine decodes a plaintext history line, deriving the ID if it has
+// none.
+func decodeLine(line []byte) (Entry, error) {
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return Entry{}, err
+	}
+	if e.Timestamp == "" {
+		return Entry{}, errors.New(`missing "ts"`)
+	}
+	if e.ID == "" {
+		e.ID = DeriveID(e.Timestamp, e.Text)
 	}
-	if err := scanner.Err(); err != nil {
+	return e, nil
+}
+
+// Search returns the entries matching q, most recent first, numbered as
+// List numbers them. If limit > 0, it stops after limit hits.
+func (s *Store) Search(q Query, limit int) ([]Hit, error) {
+	entries, err := s.List(0)
+	if err != nil {
 		return nil, err
 	}
+	return search(entries, q, limit)
+}
 
-	// Reverse to most-recent-first order.
-	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
-		entries[i], entries[j] = entries[j], entries[i]
+// Update applies fn to every entry with the given ID and rewrites the
+// history. It returns how many entries it changed, or ErrNotFound if no
+// entry has that ID.
+func (s *Store) Update(id string, fn func(*Entry)) (int, error) {
+	return update(s, id, fn)
+}
+
+// Merge adds entries from another history to this one, skipping any whose
+// timestamp and text already appear. The result is kept in oldest-first
+// order by timestamp, as if every entry had been appended on one machine.
+// It reports how many entries were added and how many were duplicates.
+func (s *Store) Merge(incoming []Entry) (added, skipped int, err error) {
+	return merge(s, incoming)
+}
+
+// HasTag reports whether the entry carries the given tag.
+func (e Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+// NormalizeTag lowercases a tag and strips surrounding space and a
+// leading '#'. It returns "" for a tag that is empty or contains
+// whitespace or commas.
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" || strings.ContainsAny(tag, " \t\n,") {
+		return ""
+	}
+	return tag
+}
+
+// rewriteAttempts bounds how often Rewrite retries when the file keeps
+// growing underneath it.
+const rewriteAttempts = 5
+
+// Rewrite replaces the history with fn's result. fn receives every entry
+// oldest first and returns the entries to keep, in file order.
+//
+// The new contents are written to a temporary file in the same directory
+// (mode 0600) and renamed over the history, so readers see either the old
+// or the new file, never a partial one. The writer lock is held
+// throughout, so appends from other vox processes wait for the rename. If
+// the file still grows while fn runs (a writer that doesn't lock), the
+// rewrite is discarded and retried so the appended entry is not lost.
+// Malformed lines, and encrypted lines that won't open, are written back
+// unchanged after the entry they followed (at the start if fn dropped
+// it), so a rewrite never loses data vox can't read.
+func (s *Store) Rewrite(fn func([]Entry) ([]Entry, error)) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	if n > 0 && n < len(entries) {
-		entries = entries[:n]
+	for range rewriteAttempts {
+		entries, bad, size, err := s.readAll()
+		if err != nil {
+			return err
+		}
+		kept, err := fn(entries)
+		if err != nil {
+			return err
+		}
+		tmp, err := s.writeTemp(kept, bad)
+		if err != nil {
+			return err
+		}
+		if changed, err := s.sizeChanged(size); err != nil || changed {
+			os.Remove(tmp)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Rename(tmp, s.path); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
 	}
+	return fmt.Errorf("history file kept changing during rewrite; try again")
+}
 
-	return entries, nil
+// writeTemp writes entries to a new temporary file next to the history
+// file and returns its path. Each bad line is written back after the
+// entry it followed, or first if that entry is gone.
+func (s *Store) writeTemp(entries []Entry, bad []BadLine) (string, error) {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, ".history-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	ids := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		ids[e.ID] = true
+	}
+	anchored := map[string][]BadLine{}
+	for _, b := range bad {
+		if b.after != "" && ids[b.after] {
+			anchored[b.after] = append(anchored[b.after], b)
+		} else {
+			anchored[""] = append(anchored[""], b)
+		}
+	}
+	writeBad := func(after string) error {
+		for _, b := range anchored[after] {
+			if _, err := w.Write(append(b.raw, '\n')); err != nil {
+				return err
+			}
+		}
+		delete(anchored, after)
+		return nil
+	}
+	err = writeBad("")
+	for _, e := range entries {
+		if err != nil {
+			break
+		}
+		var line []byte
+		if line, err = s.encodeLine(e); err != nil {
+			break
+		}
+		if _, err = w.Write(line); err != nil {
+			break
+		}
+		err = writeBad(e.ID)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(0o600)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// sizeChanged reports whether the history file no longer has the given size.
+func (s *Store) sizeChanged(size int64) (bool, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return size != 0, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.Size() != size, nil
 }
 
 // Clear removes the history file. If the file does not exist, no error is returned.
 func (s *Store) Clear() error {
-	err := os.Remove(s.path)
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.Remove(s.path)
 	if errors.Is(err, os.ErrNotExist) {
 		return nil
 	}
 	return err
 }
+
+// Resolve finds the entry a user refers to in entries, which must be in
+// List order. ref is either a 1-based position (as shown by vox ls) or an
+// entry ID; a unique ID prefix of at least 4 characters is enough.
+// Numbers of up to 3 digits are always positions. It returns the index of
+// the entry in entries; of entries sharing an ID, the first.
+func Resolve(entries []Entry, ref string) (int, error) {
+	n, err := strconv.Atoi(ref)
+	isNum := err == nil && !strings.HasPrefix(ref, "+") && !strings.HasPrefix(ref, "-")
+	if isNum && len(ref) <= 3 {
+		return resolvePosition(entries, n)
+	}
+
+	if len(ref) >= 4 {
+		found := -1
+		for i, e := range entries {
+			if !strings.HasPrefix(e.ID, strings.ToLower(ref)) {
+				continue
+			}
+			if found >= 0 && entries[found].ID != e.ID {
+				return -1, fmt.Errorf("ID prefix %q is ambiguous", ref)
+			}
+			if found >= 0 {
+				continue
+			}
+			found = i
+		}
+		if found >= 0 {
+			return found, nil
+		}
+	}
+	if isNum {
+		return resolvePosition(entries, n)
+	}
+	return -1, fmt.Errorf("no entry with ID %q", ref)
+}
+
+// SharingID returns how many entries have the given ID. Entries with the
+// same timestamp and text share one, and vox rm, edit and tag act on all
+// of them.
+func SharingID(entries []Entry, id string) int {
+	n := 0
+	for _, e := range entries {
+		if e.ID == id {
+			n++
+		}
+	}
+	return n
+}
+
+func resolvePosition(entries []Entry, n int) (int, error) {
+	if n < 1 || n > len(entries) {
+		return -1, fmt.Errorf("entry #%d not found (have %d entries)", n, len(entries))
+	}
+	return n - 1, nil
+}
@@ -1,8 +1,13 @@
 package history
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -154,3 +159,502 @@ func TestRoundTrip(t *testing.T) {
 		t.Errorf("DurationS: got %v, want %v", got.DurationS, want.DurationS)
 	}
 }
+
+func TestEntryIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// A line written before IDs existed.
+	legacy := `{"ts":"2026-02-28T10:00:00Z","text":"old","duration_s":1}` + "\n"
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+	if err := store.Append(Entry{Timestamp: "2026-02-28T11:00:00Z", Text: "new", DurationS: 2}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	entries, err := store.List(0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got, want := entries[1].ID, DeriveID("2026-02-28T10:00:00Z", "old"); got != want {
+		t.Errorf("legacy entry ID: got %q, want derived %q", got, want)
+	}
+	if got, want := entries[0].ID, DeriveID("2026-02-28T11:00:00Z", "new"); got != want {
+		t.Errorf("appended entry ID: got %q, want %q", got, want)
+	}
+	if len(entries[0].ID) != idLength {
+		t.Errorf("ID length: got %d, want %d", len(entries[0].ID), idLength)
+	}
+
+	// IDs survive further appends, unlike positions.
+	store.Append(Entry{Timestamp: "2026-02-28T12:00:00Z", Text: "newer"})
+	again, _ := store.List(0)
+	if again[2].ID != entries[1].ID || again[1].ID != entries[0].ID {
+		t.Error("IDs changed after an append")
+	}
+
+	// The ID is written to new lines but stays optional in the schema.
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"id":"`+entries[0].ID+`"`) {
+		t.Errorf("expected appended line to carry its ID, got:\n%s", data)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	entries := []Entry{
+		{ID: "a1b2c3d4"},
+		{ID: "a1b29999"},
+		{ID: "12345678"},
+		{ID: "55555555"},
+		{ID: "55555555"},
+	}
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{"1", 0, false},
+		{"3", 2, false},
+		{"6", -1, true},
+		{"0", -1, true},
+		{"a1b2c3d4", 0, false},
+		{"A1B2C", 0, false},
+		{"a1b2", -1, true}, // ambiguous
+		{"a1b", -1, true},  // too short for an ID, not a number
+		{"1234", 2, false}, // ID prefix wins over position 1234
+		{"1111", -1, true}, // neither an ID nor a valid position
+		{"ffff", -1, true},
+		{"5555", 3, false}, // entries sharing an ID aren't ambiguous
+		{"-1", -1, true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(entries, tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%q): err = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Resolve(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	for _, text := range []string{"first", "second", "third"} {
+		store.Append(Entry{Timestamp: "2026-02-28T10:00:00Z", Text: text})
+	}
+	before, _ := store.List(0)
+
+	// Remove "second" and edit "third"; the edited entry keeps its ID.
+	err := store.Rewrite(func(all []Entry) ([]Entry, error) {
+		if len(all) != 3 || all[0].Text != "first" {
+			t.Fatalf("Rewrite got %v, want all entries oldest first", all)
+		}
+		all[2].Text = "third, edited"
+		return []Entry{all[0], all[2]}, nil
+	})
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+
+	after, err := store.List(0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(after) != 2 || after[0].Text != "third, edited" || after[1].Text != "first" {
+		t.Fatalf("unexpected entries after rewrite: %v", after)
+	}
+	if after[0].ID != before[0].ID {
+		t.Errorf("edited entry ID changed: %q → %q", before[0].ID, after[0].ID)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode after rewrite: got %o, want 600", perm)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".history-*")); len(tmps) != 0 {
+		t.Errorf("temp files left behind: %v", tmps)
+	}
+}
+
+func TestRewriteRetriesOnConcurrentAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.Append(Entry{Timestamp: "2026-02-28T10:00:00Z", Text: "old"})
+
+	calls := 0
+	err := store.Rewrite(func(all []Entry) ([]Entry, error) {
+		calls++
+		if calls == 1 {
+			// A writer that ignores the lock (an older vox) appends mid-rewrite.
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(`{"ts":"2026-02-28T11:00:00Z","text":"concurrent","duration_s":0}` + "\n")
+			f.Close()
+		}
+		return all[1:], nil // drop "old"
+	})
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want a retry", calls)
+	}
+	entries, _ := store.List(0)
+	if len(entries) != 1 || entries[0].Text != "concurrent" {
+		t.Errorf("concurrent append lost: %v", entries)
+	}
+}
+
+func TestRewriteError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.Append(Entry{Timestamp: "2026-02-28T10:00:00Z", Text: "keep me"})
+
+	err := store.Rewrite(func([]Entry) ([]Entry, error) { return nil, os.ErrInvalid })
+	if err == nil {
+		t.Fatal("expected fn's error")
+	}
+	if entries, _ := store.List(0); len(entries) != 1 {
+		t.Error("history changed although fn failed")
+	}
+}
+
+func TestReadAllSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `{"ts":"2026-02-28T10:00:00Z","text":"one","duration_s":1}
+not json at all
+{"text":"no timestamp"}
+
+{"ts":"2026-02-28T11:00:00Z","text":"two","duration_s":2}
+{"ts":"2026-02-28T12:00:00Z","text":"cut off mid-wri`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+
+	entries, bad, err := store.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Text != "one" || entries[1].Text != "two" {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+	var lines []int
+	for _, b := range bad {
+		lines = append(lines, b.Line)
+	}
+	if len(lines) != 3 || lines[0] != 2 || lines[1] != 3 || lines[2] != 6 {
+		t.Errorf("bad lines: got %v, want [2 3 6]", lines)
+	}
+
+	// List tolerates the damage too, and says what it skipped.
+	if listed, err := store.List(0); err != nil || len(listed) != 2 {
+		t.Errorf("List: got %d entries, err %v", len(listed), err)
+	}
+	if got := len(store.Skipped()); got != 3 {
+		t.Errorf("Skipped after List(0): %d lines, want 3", got)
+	}
+	if _, err := store.List(1); err != nil || len(store.Skipped()) != 1 {
+		t.Errorf("Skipped after List(1): %v, err %v; want the partial last line", store.Skipped(), err)
+	}
+
+	// Appending after a partial line starts a fresh line.
+	if err := store.Append(Entry{Timestamp: "2026-02-28T13:00:00Z", Text: "three"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	entries, bad, _ = store.ReadAll()
+	if len(entries) != 3 || entries[2].Text != "three" || len(bad) != 3 {
+		t.Errorf("after append: %d entries, %d bad; want 3 and 3", len(entries), len(bad))
+	}
+}
+
+func TestRewriteKeepsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `not json at all
+{"ts":"2026-02-28T10:00:00Z","text":"one","duration_s":1}
+{"text":"no timestamp"}
+{"ts":"2026-02-28T11:00:00Z","text":"two","duration_s":2}
+{"ts":"2026-02-28T12:00:00Z","text":"three","duration_s":3}
+{"ts":"2026-02-28T12:00:00Z","text":"cut off mid-wri`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+
+	// Drop "one": the line that followed it moves to the start.
+	err := store.Rewrite(func(all []Entry) ([]Entry, error) {
+		return all[1:], nil
+	})
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{
+		"not json at all",
+		`{"text":"no timestamp"}`,
+		"two",
+		"three",
+		`{"ts":"2026-02-28T12:00:00Z","text":"cut off mid-wri`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("after Rewrite:\n%s", data)
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("line %d = %s, want %s", i+1, lines[i], w)
+		}
+	}
+}
+
+func TestReadAllLongLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	long := strings.Repeat("two hours of dictation ", 20000) // ~460KB, beyond bufio.Scanner's limit
+	if err := store.Append(Entry{Timestamp: "2026-02-28T10:00:00Z", Text: long}); err != nil {
+		t.Fatal(err)
+	}
+	entries, bad, err := store.ReadAll()
+	if err != nil || len(bad) != 0 || len(entries) != 1 || entries[0].Text != long {
+		t.Fatalf("long line not read back: %d entries, %v bad, err %v", len(entries), bad, err)
+	}
+}
+
+func TestConcurrentAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	const writers, perWriter = 16, 50
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A Store per goroutine, as separate vox processes would have.
+			store := NewStore(path)
+			for i := range perWriter {
+				e := Entry{
+					Timestamp: "2026-02-28T10:00:00Z",
+					Text:      fmt.Sprintf("writer %d entry %d %s", w, i, strings.Repeat("x", 512)),
+				}
+				if err := store.Append(e); err != nil {
+					t.Errorf("Append: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, bad, err := NewStore(path).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(bad) != 0 {
+		t.Errorf("%d corrupt lines after concurrent appends: %v", len(bad), bad[0])
+	}
+	if len(entries) != writers*perWriter {
+		t.Errorf("got %d entries, want %d", len(entries), writers*perWriter)
+	}
+}
+
+func TestConcurrentAppendsAndRewrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	const appends, rewrites = 200, 50
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		store := NewStore(path)
+		for i := range appends {
+			store.Append(Entry{Timestamp: "2026-02-28T10:00:00Z", Text: fmt.Sprintf("entry %d", i)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		store := NewStore(path)
+		for range rewrites {
+			// Rewrite every entry's text in place; nothing may be lost.
+			err := store.Rewrite(func(all []Entry) ([]Entry, error) {
+				for i := range all {
+					if !strings.HasSuffix(all[i].Text, "!") {
+						all[i].Text += "!"
+					}
+				}
+				return all, nil
+			})
+			if err != nil {
+				t.Errorf("Rewrite: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	entries, bad, err := NewStore(path).ReadAll()
+	if err != nil || len(bad) != 0 {
+		t.Fatalf("ReadAll: err %v, %d bad lines", err, len(bad))
+	}
+	if len(entries) != appends {
+		t.Errorf("got %d entries, want %d: appends lost during rewrites", len(entries), appends)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	for _, e := range []Entry{
+		{Timestamp: "2026-03-01T09:00:00Z", Text: "laptop morning"},
+		{Timestamp: "2026-03-01T17:00:00Z", Text: "laptop evening"},
+	} {
+		store.Append(e)
+	}
+
+	incoming := []Entry{
+		{Timestamp: "2026-03-01T12:00:00Z", Text: "workstation noon"},
+		{Timestamp: "2026-03-01T17:00:00Z", Text: "laptop evening"},          // already here
+		{Timestamp: "2026-03-01T17:00:00Z", Text: "workstation same second"}, // same time, other text
+		{Timestamp: "2026-02-28T08:00:00Z", Text: "workstation yesterday"},
+		{Timestamp: "2026-02-28T08:00:00Z", Text: "workstation yesterday"}, // duplicated in the import itself
+	}
+	added, skipped, err := store.Merge(incoming)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if added != 3 || skipped != 2 {
+		t.Errorf("added %d, skipped %d; want 3 and 2", added, skipped)
+	}
+
+	all, _, _ := store.ReadAll()
+	var texts []string
+	for _, e := range all {
+		texts = append(texts, e.Text)
+	}
+	want := []string{"workstation yesterday", "laptop morning", "workstation noon", "laptop evening", "workstation same second"}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("merged order:\n got %v\nwant %v", texts, want)
+	}
+
+	// Importing the same file again is a no-op, and leaves the file alone.
+	before, _ := os.Stat(path)
+	added, skipped, _ = store.Merge(incoming)
+	if added != 0 || skipped != len(incoming) {
+		t.Errorf("re-import: added %d, skipped %d", added, skipped)
+	}
+	if after, _ := os.Stat(path); !os.SameFile(before, after) {
+		t.Error("re-import rewrote the history file")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "first"})
+	store.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "second"})
+
+	id := DeriveID("2026-03-01T09:00:00Z", "first")
+	n, err := store.Update(id, func(e *Entry) {
+		e.Tags = []string{"idea"}
+		e.Note = "follow up"
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("Update: %d, %v", n, err)
+	}
+
+	all, _, _ := store.ReadAll()
+	if !all[0].HasTag("idea") || all[0].Note != "follow up" || all[0].ID != id {
+		t.Errorf("updated entry = %+v", all[0])
+	}
+	if all[1].HasTag("idea") || all[1].Note != "" {
+		t.Errorf("other entry changed: %+v", all[1])
+	}
+
+	if _, err := store.Update("ffffffff", func(*Entry) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update unknown id: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateSharedID(t *testing.T) {
+	// Two dictations of the same words in the same second share an ID;
+	// Update changes both, as vox rm removes both.
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "same"})
+	store.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "same"})
+	store.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "other"})
+
+	id := DeriveID("2026-03-01T09:00:00Z", "same")
+	n, err := store.Update(id, func(e *Entry) { e.Text = "edited" })
+	if err != nil || n != 2 {
+		t.Fatalf("Update: %d, %v; want 2 entries changed", n, err)
+	}
+	all, _, _ := store.ReadAll()
+	if all[0].Text != "edited" || all[1].Text != "edited" || all[2].Text != "other" {
+		t.Errorf("entries after Update = %+v", all)
+	}
+	if got := SharingID(all, id); got != 2 {
+		t.Errorf("SharingID = %d, want 2", got)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"idea", "idea"},
+		{"  #Robot ", "robot"},
+		{"#", ""},
+		{"", ""},
+		{"two words", ""},
+		{"a,b", ""},
+		{"follow-up", "follow-up"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.in); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+
+	want := Entry{
+		Timestamp: "2026-02-28T14:30:00Z",
+		Text:      "standup notes",
+		DurationS: 725,
+		Source:    SourceFile,
+		File:      "/home/me/standup.m4a",
+		Provider:  "openai",
+		Model:     "gpt-4o-mini-transcribe",
+		Language:  "english",
+		Chunks:    3,
+		CostUSD:   0.03625,
+	}
+	store.Append(want)
+	want.ID = DeriveID(want.Timestamp, want.Text)
+
+	entries, err := store.List(0)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List: %v, %d entries", err, len(entries))
+	}
+	if !reflect.DeepEqual(entries[0], want) {
+		t.Errorf("got %+v\nwant %+v", entries[0], want)
+	}
+
+	// Entries without metadata keep the original line format.
+	store.Append(Entry{Timestamp: "2026-02-28T15:00:00Z", Text: "plain", DurationS: 1})
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if want := `{"ts":"2026-02-28T15:00:00Z","text":"plain","duration_s":1,"id":"cacf5c56"}`; lines[1] != want {
+		t.Errorf("plain entry line = %s", lines[1])
+	}
+}
@@ -0,0 +1,31 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lock takes the store's exclusive writer lock and returns a function that
+// releases it. The lock lives in a sibling file (history.jsonl.lock)
+// because Rewrite replaces the history file itself, and a lock held on a
+// replaced inode would protect nothing.
+//
+// Locks are advisory: they order vox processes that use them. Writers
+// that don't (older vox versions) are still caught by Rewrite's size check.
+func (s *Store) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		funlock(f)
+		f.Close()
+	}, nil
+}
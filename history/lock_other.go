@@ -0,0 +1,12 @@
+//go:build !unix
+
+package history
+
+import "os"
+
+// flock is a no-op where flock(2) is unavailable; appends then rely on
+// append-mode atomicity and rewrites on their size check alone.
+func flock(f *os.File) error { return nil }
+
+// funlock is a no-op where flock(2) is unavailable.
+func funlock(f *os.File) error { return nil }
@@ -0,0 +1,111 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// Retention limits how much history is kept. Zero fields mean no limit.
+type Retention struct {
+	// MaxAge drops entries whose timestamp is older than this.
+	MaxAge time.Duration
+	// MaxEntries keeps only the most recent entries.
+	MaxEntries int
+}
+
+// IsZero reports whether r imposes no limits.
+func (r Retention) IsZero() bool {
+	return r.MaxAge <= 0 && r.MaxEntries <= 0
+}
+
+// Apply returns the entries r keeps at time now. entries must be in file
+// order (oldest first); the result shares no storage with it. Entries
+// with an unparseable timestamp are never dropped for age.
+func (r Retention) Apply(entries []Entry, now time.Time) []Entry {
+	kept := make([]Entry, 0, len(entries))
+	cutoff := now.Add(-r.MaxAge)
+	for _, e := range entries {
+		if r.MaxAge > 0 {
+			if t, err := e.Time(); err == nil && t.Before(cutoff) {
+				continue
+			}
+		}
+		kept = append(kept, e)
+	}
+	if r.MaxEntries > 0 && len(kept) > r.MaxEntries {
+		kept = kept[len(kept)-r.MaxEntries:]
+	}
+	return kept
+}
+
+// SetRetention makes Append enforce r after every write. Prune uses it
+// too.
+func (s *Store) SetRetention(r Retention) {
+	s.retention = r
+}
+
+// Prune removes the entries the store's retention policy no longer keeps
+// and compacts the file with Rewrite. It reports how many entries were
+// removed. With no policy, or nothing to remove, the file is left
+// untouched.
+func (s *Store) Prune(now time.Time) (int, error) {
+	return prune(s, s.retention, now)
+}
+
+// expired reports whether the retention policy has anything to drop,
+// without reading the whole history: the count is checked on the last
+// MaxEntries+1 lines, and the age on the oldest entry only, since
+// entries are kept in timestamp order.
+func (s *Store) expired(now time.Time) (bool, error) {
+	r := s.retention
+	if r.MaxEntries > 0 {
+		recent, err := s.readLast(r.MaxEntries + 1)
+		if err != nil {
+			return false, err
+		}
+		if len(recent) > r.MaxEntries {
+			return true, nil
+		}
+	}
+	if r.MaxAge > 0 {
+		t, ok, err := s.oldest()
+		if err != nil || !ok {
+			return false, err
+		}
+		return t.Before(now.Add(-r.MaxAge)), nil
+	}
+	return false, nil
+}
+
+// oldest returns the timestamp of the first entry with a valid one.
+func (s *Store) oldest() (time.Time, bool, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if e, perr := s.parseLine(line); perr == nil {
+			if t, terr := e.Time(); terr == nil {
+				return t, true, nil
+			}
+		} else if errors.Is(perr, ErrEncrypted) {
+			return time.Time{}, false, perr
+		}
+		if errors.Is(err, io.EOF) {
+			return time.Time{}, false, nil
+		}
+		if err != nil {
+			return time.Time{}, false, err
+		}
+	}
+}
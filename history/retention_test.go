@@ -0,0 +1,98 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionApply(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Timestamp: "2026-01-01T00:00:00Z", Text: "january"},
+		{Timestamp: "garbage", Text: "unparseable"},
+		{Timestamp: "2026-03-01T00:00:00Z", Text: "march 1"},
+		{Timestamp: "2026-03-30T00:00:00Z", Text: "march 30"},
+		{Timestamp: "2026-03-31T11:00:00Z", Text: "today"},
+	}
+
+	tests := []struct {
+		name string
+		r    Retention
+		want string
+	}{
+		{"no limits", Retention{}, "january|unparseable|march 1|march 30|today"},
+		{"max age", Retention{MaxAge: 60 * 24 * time.Hour}, "unparseable|march 1|march 30|today"},
+		{"max entries", Retention{MaxEntries: 2}, "march 30|today"},
+		{"both", Retention{MaxAge: 7 * 24 * time.Hour, MaxEntries: 10}, "unparseable|march 30|today"},
+		{"entries larger than history", Retention{MaxEntries: 100}, "january|unparseable|march 1|march 30|today"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var texts []string
+			for _, e := range tt.r.Apply(entries, now) {
+				texts = append(texts, e.Text)
+			}
+			if got := strings.Join(texts, "|"); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if len(entries) != 5 || entries[0].Text != "january" {
+		t.Error("Apply modified its input")
+	}
+}
+
+func TestAppendPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.Append(Entry{Timestamp: "2020-01-01T00:00:00Z", Text: "ancient"})
+	for i := range 4 {
+		store.Append(Entry{Timestamp: time.Now().UTC().Format(time.RFC3339), Text: strings.Repeat("x", i+1)})
+	}
+
+	store.SetRetention(Retention{MaxAge: 24 * time.Hour, MaxEntries: 3})
+	if n, err := store.Prune(time.Now()); err != nil || n != 2 {
+		t.Fatalf("Prune = %d, %v; want 2 removed", n, err)
+	}
+	if n, err := store.Prune(time.Now()); err != nil || n != 0 {
+		t.Fatalf("second Prune = %d, %v; want nothing removed", n, err)
+	}
+
+	if err := store.Append(Entry{Timestamp: time.Now().UTC().Format(time.RFC3339), Text: "newest"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	all, _, _ := store.ReadAll()
+	var texts []string
+	for _, e := range all {
+		texts = append(texts, e.Text)
+	}
+	if got := strings.Join(texts, "|"); got != "xxx|xxxx|newest" {
+		t.Errorf("after append: %s", got)
+	}
+}
+
+func TestAppendRewritesOnlyWhenExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path)
+	store.SetRetention(Retention{MaxAge: 24 * time.Hour, MaxEntries: 3})
+	now := func() string { return time.Now().UTC().Format(time.RFC3339) }
+
+	store.Append(Entry{Timestamp: now(), Text: "one"})
+	before, _ := os.Stat(path)
+	store.Append(Entry{Timestamp: now(), Text: "two"})
+	store.Append(Entry{Timestamp: now(), Text: "three"})
+	if after, _ := os.Stat(path); !os.SameFile(before, after) {
+		t.Error("Append rewrote the history although nothing expired")
+	}
+
+	store.Append(Entry{Timestamp: now(), Text: "four"})
+	if after, _ := os.Stat(path); os.SameFile(before, after) {
+		t.Error("Append didn't prune past max entries")
+	}
+	if all, _, _ := store.ReadAll(); len(all) != 3 || all[0].Text != "two" {
+		t.Errorf("after prune: %+v", all)
+	}
+}
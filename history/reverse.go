@@ -0,0 +1,100 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+)
+
+// reverseBlockSize is how much readLast reads per step from the end of
+// the file. A variable so tests can force lines across block boundaries.
+var reverseBlockSize int64 = 64 << 10
+
+// readLast returns up to n entries from the end of the history file, most
+// recent first. It reads the file backwards in blocks and stops as soon as
+// it has n entries, so its cost depends on n rather than on the size of
+// the history. Malformed lines are skipped, as in ReadAll. If an
+// encrypted line won't open, it falls back to a full read so that a
+// wrong key is told apart from a damaged line.
+func (s *Store) readLast(n int) ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Entry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	unopened := false
+	var carry []byte // start of a line that began in a block not yet read
+	pos := info.Size()
+	for pos > 0 && len(entries) < n {
+		size := min(reverseBlockSize, pos)
+		pos -= size
+		buf := make([]byte, size, size+int64(len(carry)))
+		if _, err := f.ReadAt(buf, pos); err != nil {
+			return nil, err
+		}
+		buf = append(buf, carry...)
+
+		// Everything after a newline in buf is a complete line.
+		for len(entries) < n {
+			i := bytes.LastIndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			if entries, err = s.appendLine(entries, buf[i+1:], &unopened); err != nil {
+				return nil, err
+			}
+			buf = buf[:i]
+		}
+		carry = buf
+	}
+	// At the start of the file, what's left is the first line.
+	if pos == 0 && len(entries) < n {
+		if entries, err = s.appendLine(entries, carry, &unopened); err != nil {
+			return nil, err
+		}
+	}
+	if unopened {
+		all, bad, err := s.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		s.skipped = bad
+		slices.Reverse(all)
+		return all[:min(n, len(all))], nil
+	}
+	return entries, nil
+}
+
+// appendLine parses line and appends it to entries unless it is blank or
+// malformed, in which case it is added to s.skipped. It sets *unopened
+// for an encrypted line that didn't open and fails only if the store has
+// no cipher for one.
+func (s *Store) appendLine(entries []Entry, line []byte, unopened *bool) ([]Entry, error) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return entries, nil
+	}
+	e, err := s.parseLine(line)
+	switch {
+	case errors.Is(err, ErrEncrypted):
+		return nil, err
+	case errors.Is(err, errDecrypt):
+		*unopened = true
+		s.skipped = append(s.skipped, BadLine{Err: err})
+		return entries, nil
+	case err != nil:
+		s.skipped = append(s.skipped, BadLine{Err: err})
+		return entries, nil
+	}
+	return append(entries, e), nil
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListReadsBackwards(t *testing.T) {
+	orig := reverseBlockSize
+	t.Cleanup(func() { reverseBlockSize = orig })
+
+	var b strings.Builder
+	for i := range 40 {
+		// Vary line lengths so lines straddle block boundaries, including
+		// one much longer than a block.
+		text := fmt.Sprintf("entry %d %s", i, strings.Repeat("word ", i%7))
+		if i == 17 {
+			text = strings.Repeat("long ", 200)
+		}
+		line, _ := json.Marshal(Entry{Timestamp: "2026-02-28T10:00:00Z", Text: text})
+		b.Write(line)
+		b.WriteByte('\n')
+		if i%11 == 5 {
+			b.WriteString("garbage line\n\n")
+		}
+	}
+
+	for _, content := range []string{b.String(), strings.TrimSuffix(b.String(), "\n")} {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		store := NewStore(path)
+		all, _, err := store.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Reverse(all)
+
+		for _, block := range []int64{7, 64, 100, 1 << 16} {
+			reverseBlockSize = block
+			for _, n := range []int{1, 2, 5, 18, 39, 40, 100} {
+				got, err := store.List(n)
+				if err != nil {
+					t.Fatalf("List(%d) with block %d: %v", n, block, err)
+				}
+				want := all[:min(n, len(all))]
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("List(%d) with block %d: got %d entries, differs from full read", n, block, len(got))
+				}
+			}
+		}
+	}
+}
+
+// writeBenchHistory generates a history file with n entries.
+func writeBenchHistory(b *testing.B, n int) *Store {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "history.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range n {
+		enc.Encode(Entry{
+			Timestamp: start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339),
+			Text:      fmt.Sprintf("Dictation number %d about the contact sensor config and the gantry collision boundary", i),
+			DurationS: float64(i%120) + 0.5,
+		})
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+	return NewStore(path)
+}
+
+func BenchmarkList20(b *testing.B) {
+	store := writeBenchHistory(b, 100_000)
+	for b.Loop() {
+		if _, err := store.List(20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListAll(b *testing.B) {
+	store := writeBenchHistory(b, 100_000)
+	for b.Loop() {
+		if _, err := store.List(0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
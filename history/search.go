@@ -0,0 +1,72 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Query selects history entries by text, date range and duration.
+// Zero-valued fields do not filter.
+type Query struct {
+	Text        string    // case-insensitive substring, or a regexp if Regex is set
+	Regex       bool      // treat Text as a regular expression (RE2 syntax)
+	Since       time.Time // entries at or after this time
+	Until       time.Time // entries before this time
+	MinDuration float64   // minimum duration in seconds
+	Tags        []string  // entries carrying all of these tags
+}
+
+// Matcher is a compiled Query.
+type Matcher struct {
+	q  Query
+	re *regexp.Regexp
+}
+
+// Compile validates the query and prepares it for matching.
+func (q Query) Compile() (*Matcher, error) {
+	m := &Matcher{q: q}
+	if q.Text == "" {
+		return m, nil
+	}
+	pattern := "(?i)" + regexp.QuoteMeta(q.Text)
+	if q.Regex {
+		pattern = q.Text
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	m.re = re
+	return m, nil
+}
+
+// Match reports whether e satisfies the query. It also returns the byte
+// offsets of each text match in e.Text, as [start, end) pairs.
+func (m *Matcher) Match(e Entry) ([][]int, bool) {
+	if m.q.MinDuration > 0 && e.DurationS < m.q.MinDuration {
+		return nil, false
+	}
+	for _, tag := range m.q.Tags {
+		if !e.HasTag(tag) {
+			return nil, false
+		}
+	}
+	if !m.q.Since.IsZero() || !m.q.Until.IsZero() {
+		t, err := e.Time()
+		if err != nil {
+			return nil, false
+		}
+		if !m.q.Since.IsZero() && t.Before(m.q.Since) {
+			return nil, false
+		}
+		if !m.q.Until.IsZero() && !t.Before(m.q.Until) {
+			return nil, false
+		}
+	}
+	if m.re == nil {
+		return nil, true
+	}
+	locs := m.re.FindAllStringIndex(e.Text, -1)
+	return locs, len(locs) > 0
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryMatch(t *testing.T) {
+	e := Entry{Timestamp: "2026-03-10T12:00:00Z", Text: "Move the Sensor config to YAML. The sensor list breaks.", DurationS: 12, Tags: []string{"idea", "robot"}}
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		q        Query
+		want     bool
+		wantLocs int
+	}{
+		{"empty query matches", Query{}, true, 0},
+		{"substring is case-insensitive", Query{Text: "sensor"}, true, 2},
+		{"substring no match", Query{Text: "gantry"}, false, 0},
+		{"regex metachars are literal in substring mode", Query{Text: "yaml."}, true, 1},
+		{"regex", Query{Text: `S\w+r`, Regex: true}, true, 1},
+		{"regex is case-sensitive", Query{Text: "YAML", Regex: true}, true, 1},
+		{"since before", Query{Since: day(9)}, true, 0},
+		{"since after", Query{Since: day(11)}, false, 0},
+		{"until after", Query{Until: day(11)}, true, 0},
+		{"until is exclusive", Query{Until: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)}, false, 0},
+		{"min duration met", Query{MinDuration: 12}, true, 0},
+		{"min duration not met", Query{MinDuration: 12.5}, false, 0},
+		{"tag", Query{Tags: []string{"idea"}}, true, 0},
+		{"all tags required", Query{Tags: []string{"idea", "todo"}}, false, 0},
+		{"text and filters", Query{Text: "config", Since: day(1), Until: day(31), MinDuration: 5}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.q.Compile()
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			locs, ok := m.Match(e)
+			if ok != tt.want {
+				t.Fatalf("Match = %v, want %v", ok, tt.want)
+			}
+			if len(locs) != tt.wantLocs {
+				t.Errorf("got %d match locations, want %d", len(locs), tt.wantLocs)
+			}
+		})
+	}
+}
+
+func TestQueryCompileBadRegex(t *testing.T) {
+	if _, err := (Query{Text: "(unclosed", Regex: true}).Compile(); err == nil {
+		t.Fatal("expected error for invalid regex")
+	}
+}
+
+func TestQueryBadTimestamp(t *testing.T) {
+	m, _ := Query{Since: time.Unix(0, 0)}.Compile()
+	if _, ok := m.Match(Entry{Timestamp: "garbage"}); ok {
+		t.Error("entry with unparseable timestamp should not match a date filter")
+	}
+}
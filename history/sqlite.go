@@ -0,0 +1,448 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "modernc.org/sqlite" // registers the pure-Go "sqlite" driver
+)
+
+// SQLite is a Backend that keeps history in an SQLite database, with an
+// FTS5 trigram index over the text so search doesn't scan every entry.
+//
+// Each row holds the entry as a JSONL history line in the data column,
+// so every field round-trips; id, ts and text are copies for indexing.
+// Rows are numbered by an autoincrement seq that keeps append order.
+type SQLite struct {
+	path      string
+	retention Retention
+	skipped   []BadLine
+}
+
+var (
+	_ Backend = (*Store)(nil)
+	_ Backend = (*SQLite)(nil)
+)
+
+// NewSQLite returns an SQLite backend for the database at path. The
+// database is created on first use.
+func NewSQLite(path string) *SQLite {
+	return &SQLite{path: path}
+}
+
+// sqliteSchema creates the tables on first use. The FTS table is an
+// external-content index over entries, kept current by triggers.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	seq  INTEGER PRIMARY KEY AUTOINCREMENT,
+	id   TEXT NOT NULL,
+	ts   TEXT NOT NULL,
+	text TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS entries_id ON entries(id);
+CREATE VIRTUAL TABLE IF NOT EXISTS entries_fts USING fts5(
+	text, content='entries', content_rowid='seq', tokenize='trigram'
+);
+CREATE TRIGGER IF NOT EXISTS entries_ai AFTER INSERT ON entries BEGIN
+	INSERT INTO entries_fts(rowid, text) VALUES (new.seq, new.text);
+END;
+CREATE TRIGGER IF NOT EXISTS entries_ad AFTER DELETE ON entries BEGIN
+	INSERT INTO entries_fts(entries_fts, rowid, text) VALUES ('delete', old.seq, old.text);
+END;
+CREATE TRIGGER IF NOT EXISTS entries_au AFTER UPDATE ON entries BEGIN
+	INSERT INTO entries_fts(entries_fts, rowid, text) VALUES ('delete', old.seq, old.text);
+	INSERT INTO entries_fts(rowid, text) VALUES (new.seq, new.text);
+END;
+`
+
+// open opens the database, creating it (mode 0600, in a 0700 directory)
+// and its schema if needed. Transactions take the write lock up front so
+// a read-modify-write can't be overtaken by another vox process; busy
+// writers wait rather than fail. Deleted text is overwritten on disk.
+func (s *SQLite) open() (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, err
+	}
+	// SQLite would create the file 0644; history is private.
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "secure_delete(on)")
+	params.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite", "file:"+s.path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", s.path, err)
+	}
+	return db, nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common for reading.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// readRows runs a query selecting (seq, data) and decodes the entries.
+// Rows that don't decode are reported by seq in bad.
+func readRows(q querier, query string, args ...any) ([]Entry, []BadLine, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	var bad []BadLine
+	for rows.Next() {
+		var seq int
+		var data string
+		if err := rows.Scan(&seq, &data); err != nil {
+			return nil, nil, err
+		}
+		e, err := decodeLine([]byte(data))
+		if err != nil {
+			bad = append(bad, BadLine{Line: seq, Err: err})
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, bad, rows.Err()
+}
+
+// insert adds entries in order, assigning IDs.
+func insert(tx *sql.Tx, entries []Entry) error {
+	stmt, err := tx.Prepare(`INSERT INTO entries (id, ts, text, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range entries {
+		if e.ID == "" {
+			e.ID = DeriveID(e.Timestamp, e.Text)
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(e.ID, e.Timestamp, e.Text, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append adds an entry, then prunes per the retention policy.
+func (s *SQLite) Append(e Entry) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insert(tx, []Entry{e}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if s.retention.IsZero() {
+		return nil
+	}
+	now := time.Now()
+	expired, err := s.expired(db, now)
+	if err == nil && expired {
+		_, err = s.Prune(now)
+	}
+	if err != nil {
+		return fmt.Errorf("pruning history: %w", err)
+	}
+	return nil
+}
+
+// expired reports whether the retention policy has anything to drop,
+// from the row count and the oldest entry's timestamp.
+func (s *SQLite) expired(db *sql.DB, now time.Time) (bool, error) {
+	r := s.retention
+	if r.MaxEntries > 0 {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM entries`).Scan(&n); err != nil {
+			return false, err
+		}
+		if n > r.MaxEntries {
+			return true, nil
+		}
+	}
+	if r.MaxAge <= 0 {
+		return false, nil
+	}
+	rows, err := db.Query(`SELECT ts FROM entries ORDER BY seq`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ts string
+		if err := rows.Scan(&ts); err != nil {
+			return false, err
+		}
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			return t.Before(now.Add(-r.MaxAge)), nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// List returns up to n entries (all if n == 0), most recent first.
+// Rows that don't decode are skipped; Skipped reports them.
+func (s *SQLite) List(n int) ([]Entry, error) {
+	s.skipped = nil
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	query := `SELECT seq, data FROM entries ORDER BY seq DESC`
+	if n > 0 {
+		query += fmt.Sprintf(" LIMIT %d", n)
+	}
+	entries, bad, err := readRows(db, query)
+	s.skipped = bad
+	return entries, err
+}
+
+// Skipped returns the rows the last List or Search couldn't decode.
+func (s *SQLite) Skipped() []BadLine {
+	return s.skipped
+}
+
+// ReadAll returns every entry oldest first. Rows that don't decode are
+// reported in bad, numbered by their seq.
+func (s *SQLite) ReadAll() ([]Entry, []BadLine, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+	return readRows(db, `SELECT seq, data FROM entries ORDER BY seq`)
+}
+
+// Search returns entries matching q, most recent first, numbered as List
+// numbers them. Plain-text queries of three or more characters are
+// narrowed with the trigram index first; the other filters, regexps and
+// shorter text are checked entry by entry.
+func (s *SQLite) Search(q Query, limit int) ([]Hit, error) {
+	m, err := q.Compile()
+	if err != nil {
+		return nil, err
+	}
+	if q.Regex || utf8.RuneCountInString(q.Text) < 3 {
+		entries, err := s.List(0)
+		if err != nil {
+			return nil, err
+		}
+		return search(entries, q, limit)
+	}
+
+	s.skipped = nil
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// A quoted FTS5 string is a phrase; with the trigram tokenizer that
+	// is a case-insensitive substring match.
+	phrase := `"` + strings.ReplaceAll(q.Text, `"`, `""`) + `"`
+	rows, err := db.Query(`
+		SELECT seq, pos, data FROM (
+			SELECT seq, data, ROW_NUMBER() OVER (ORDER BY seq DESC) AS pos FROM entries
+		) WHERE seq IN (SELECT rowid FROM entries_fts WHERE entries_fts MATCH ?)
+		ORDER BY pos`, phrase)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		var seq, pos int
+		var data string
+		if err := rows.Scan(&seq, &pos, &data); err != nil {
+			return nil, err
+		}
+		e, err := decodeLine([]byte(data))
+		if err != nil {
+			s.skipped = append(s.skipped, BadLine{Line: seq, Err: err})
+			continue
+		}
+		locs, ok := m.Match(e)
+		if !ok {
+			continue
+		}
+		hits = append(hits, Hit{Entry: e, Pos: pos, Locs: locs})
+		if limit > 0 && len(hits) == limit {
+			break
+		}
+	}
+	return hits, rows.Err()
+}
+
+// Rewrite replaces every entry with fn's result in one transaction. fn
+// receives every entry oldest first. Rows that don't decode are left as
+// they are, ahead of the rewritten entries, so a rewrite never loses data
+// vox can't read.
+func (s *SQLite) Rewrite(fn func([]Entry) ([]Entry, error)) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entries, bad, err := readRows(tx, `SELECT seq, data FROM entries ORDER BY seq`)
+	if err != nil {
+		return err
+	}
+	kept, err := fn(entries)
+	if err != nil {
+		return err
+	}
+	del := `DELETE FROM entries`
+	args := make([]any, len(bad))
+	if len(bad) > 0 {
+		for i, b := range bad {
+			args[i] = b.Line
+		}
+		del += ` WHERE seq NOT IN (?` + strings.Repeat(`, ?`, len(bad)-1) + `)`
+	}
+	if _, err := tx.Exec(del, args...); err != nil {
+		return err
+	}
+	if err := insert(tx, kept); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update applies fn to every entry with the given ID, changing only their
+// rows, and returns how many there were. It returns ErrNotFound if no
+// entry has that ID.
+func (s *SQLite) Update(id string, fn func(*Entry)) (int, error) {
+	db, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	type row struct {
+		seq  int
+		data string
+	}
+	var matched []row
+	rows, err := tx.Query(`SELECT seq, data FROM entries WHERE id = ? ORDER BY seq`, id)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.seq, &r.data); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		matched = append(matched, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(matched) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	for _, r := range matched {
+		e, err := decodeLine([]byte(r.data))
+		if err != nil {
+			return 0, err
+		}
+		fn(&e)
+		line, err := json.Marshal(e)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`UPDATE entries SET id = ?, ts = ?, text = ?, data = ? WHERE seq = ?`,
+			e.ID, e.Timestamp, e.Text, string(line), r.seq)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(matched), tx.Commit()
+}
+
+// Merge adds the incoming entries whose timestamp and text aren't
+// already present, keeping the history in timestamp order.
+func (s *SQLite) Merge(incoming []Entry) (added, skipped int, err error) {
+	return merge(s, incoming)
+}
+
+// SetRetention makes Append enforce r after every write.
+func (s *SQLite) SetRetention(r Retention) {
+	s.retention = r
+}
+
+// Prune removes the entries the retention policy no longer keeps and
+// reports how many.
+func (s *SQLite) Prune(now time.Time) (int, error) {
+	return prune(s, s.retention, now)
+}
+
+// Clear removes every entry and compacts the database.
+func (s *SQLite) Clear() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(`DELETE FROM entries`); err != nil {
+		return err
+	}
+	_, err = db.Exec(`VACUUM`)
+	return err
+}
@@ -0,0 +1,185 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestSQLite(t *testing.T) *SQLite {
+	t.Helper()
+	return NewSQLite(filepath.Join(t.TempDir(), "vox", "history.db"))
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	db := newTestSQLite(t)
+	want := []Entry{
+		{Timestamp: "2026-03-01T09:00:00Z", Text: "first", DurationS: 1.5},
+		{Timestamp: "2026-03-01T10:00:00Z", Text: "second", Tags: []string{"idea"}, Note: "n"},
+		{Timestamp: "2026-03-01T08:00:00Z", Text: "out of order but appended last"},
+	}
+	for _, e := range want {
+		if err := db.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	for i := range want {
+		want[i].ID = DeriveID(want[i].Timestamp, want[i].Text)
+	}
+
+	all, bad, err := db.ReadAll()
+	if err != nil || len(bad) != 0 {
+		t.Fatalf("ReadAll: %v, bad %v", err, bad)
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("ReadAll:\n got %+v\nwant %+v", all, want)
+	}
+
+	last, err := db.List(2)
+	if err != nil || len(last) != 2 || last[0].Text != "out of order but appended last" || last[1].Text != "second" {
+		t.Errorf("List(2) = %+v, %v", last, err)
+	}
+
+	info, err := os.Stat(db.path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("database mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func TestSQLiteSearch(t *testing.T) {
+	db := newTestSQLite(t)
+	for _, text := range []string{
+		"Move the Sensor config to YAML",
+		"gantry collision boundary",
+		"the sensor list breaks",
+		"unrelated",
+	} {
+		db.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: text})
+	}
+
+	tests := []struct {
+		name  string
+		q     Query
+		limit int
+		want  []int // positions
+	}{
+		{"fts substring, case-insensitive", Query{Text: "SENSOR"}, 0, []int{2, 4}},
+		{"fts inside a word", Query{Text: "ollis"}, 0, []int{3}},
+		{"fts with limit", Query{Text: "sensor"}, 1, []int{2}},
+		{"phrase with quotes", Query{Text: `say "hi"`}, 0, nil},
+		{"short text scans", Query{Text: "to"}, 0, []int{4}},
+		{"regex scans", Query{Text: `S\w+r`, Regex: true}, 0, []int{4}},
+		{"no text", Query{}, 2, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := db.Search(tt.q, tt.limit)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []int
+			for _, h := range hits {
+				got = append(got, h.Pos)
+				if tt.q.Text != "" && len(h.Locs) == 0 {
+					t.Errorf("hit %d has no match locations", h.Pos)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("positions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteUpdateRewriteClear(t *testing.T) {
+	db := newTestSQLite(t)
+	db.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "keep"})
+	db.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "drop"})
+	id := DeriveID("2026-03-01T09:00:00Z", "keep")
+
+	if n, err := db.Update(id, func(e *Entry) { e.Text = "kept and edited" }); err != nil || n != 1 {
+		t.Fatalf("Update: %d, %v", n, err)
+	}
+	if _, err := db.Update("ffffffff", func(*Entry) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update unknown id: err = %v", err)
+	}
+	if hits, _ := db.Search(Query{Text: "edited"}, 0); len(hits) != 1 || hits[0].Entry.ID != id {
+		t.Errorf("index not updated: %+v", hits)
+	}
+
+	err := db.Rewrite(func(all []Entry) ([]Entry, error) {
+		return all[:1], nil
+	})
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	if hits, _ := db.Search(Query{Text: "drop"}, 0); len(hits) != 0 {
+		t.Errorf("removed entry still indexed: %+v", hits)
+	}
+	all, _, _ := db.ReadAll()
+	if len(all) != 1 || all[0].Text != "kept and edited" {
+		t.Errorf("after Rewrite: %+v", all)
+	}
+
+	if err := db.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if all, _, _ := db.ReadAll(); len(all) != 0 {
+		t.Errorf("after Clear: %+v", all)
+	}
+}
+
+func TestSQLiteRewriteKeepsBadRows(t *testing.T) {
+	db := newTestSQLite(t)
+	db.Append(Entry{Timestamp: "2026-03-01T09:00:00Z", Text: "damaged"})
+	db.Append(Entry{Timestamp: "2026-03-01T10:00:00Z", Text: "fine"})
+	conn, err := db.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Exec(`UPDATE entries SET data = 'not json' WHERE text = 'damaged'`)
+	conn.Close()
+
+	if listed, _ := db.List(0); len(listed) != 1 || len(db.Skipped()) != 1 {
+		t.Errorf("List: %d entries, skipped %v; want 1 and 1", len(listed), db.Skipped())
+	}
+	err = db.Rewrite(func(all []Entry) ([]Entry, error) {
+		all[0].Text = "fine, edited"
+		return all, nil
+	})
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	all, bad, _ := db.ReadAll()
+	if len(all) != 1 || all[0].Text != "fine, edited" || len(bad) != 1 {
+		t.Errorf("after Rewrite: %+v, bad %v; want the damaged row kept", all, bad)
+	}
+}
+
+func TestSQLiteConcurrentAppends(t *testing.T) {
+	db := newTestSQLite(t)
+	const writers, each = 4, 10
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate backends, as separate vox processes would have.
+			s := NewSQLite(db.path)
+			for i := range each {
+				ts := time.Date(2026, 3, 1, 0, w, i, 0, time.UTC).Format(time.RFC3339)
+				if err := s.Append(Entry{Timestamp: ts, Text: "x"}); err != nil {
+					t.Errorf("Append: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if all, _, _ := db.ReadAll(); len(all) != writers*each {
+		t.Errorf("got %d entries, want %d", len(all), writers*each)
+	}
+}
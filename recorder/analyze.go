@@ -0,0 +1,130 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+const (
+	// silentPeakDBFS is the peak level below which a recording counts as
+	// near-silent, e.g. from a muted microphone.
+	silentPeakDBFS = -40.0
+	// clippedRatio is the fraction of full-scale samples above which a
+	// recording counts as heavily clipped.
+	clippedRatio = 0.005
+)
+
+// Silent reports whether the audio is empty or near-silent.
+func (l Levels) Silent() bool {
+	return l.Samples == 0 || l.PeakDBFS < silentPeakDBFS
+}
+
+// ClippedFraction returns the fraction of samples at full scale.
+func (l Levels) ClippedFraction() float64 {
+	if l.Samples == 0 {
+		return 0
+	}
+	return float64(l.Clipped) / float64(l.Samples)
+}
+
+// Clipping reports whether the audio is heavily clipped.
+func (l Levels) Clipping() bool {
+	return l.ClippedFraction() > clippedRatio
+}
+
+// errNotPCM16 is returned by analyzeWAV for WAV files that are not 16-bit PCM.
+var errNotPCM16 = errors.New("not a 16-bit PCM WAV file")
+
+// Analyze measures the levels of an audio file. 16-bit PCM WAV files are
+// read directly; any other format is decoded with sox.
+func Analyze(path string) (Levels, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Levels{}, err
+	}
+	defer f.Close()
+
+	l, err := analyzeWAV(f)
+	if err == nil {
+		return l, nil
+	}
+	if !errors.Is(err, errNotPCM16) {
+		return Levels{}, err
+	}
+	return analyzeWithSoX(path)
+}
+
+// analyzeWAV walks the RIFF chunks of a WAV file and meters its data chunk.
+func analyzeWAV(r io.Reader) (Levels, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil || string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return Levels{}, errNotPCM16
+	}
+
+	le := binary.LittleEndian
+	pcm16 := false
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return Levels{}, fmt.Errorf("reading WAV: no data chunk")
+		}
+		id, size := string(hdr[0:4]), int64(le.Uint32(hdr[4:]))
+
+		switch id {
+		case "fmt ":
+			// Only the first 16 bytes matter; skip any extension rather
+			// than trusting the declared size for an allocation.
+			var fmtChunk [16]byte
+			if size < 16 {
+				return Levels{}, errNotPCM16
+			}
+			if _, err := io.ReadFull(r, fmtChunk[:]); err != nil {
+				return Levels{}, errNotPCM16
+			}
+			if _, err := io.CopyN(io.Discard, r, size-16+size%2); err != nil {
+				return Levels{}, errNotPCM16
+			}
+			pcm16 = le.Uint16(fmtChunk[0:]) == 1 && le.Uint16(fmtChunk[14:]) == 16
+		case "data":
+			if !pcm16 {
+				return Levels{}, errNotPCM16
+			}
+			m := newMeter(nil)
+			// A data size of 0 is left by writers that never finalized
+			// the header; read to EOF in that case.
+			src := r
+			if size > 0 {
+				src = io.LimitReader(r, size)
+			}
+			if _, err := io.Copy(m, src); err != nil {
+				return Levels{}, fmt.Errorf("reading WAV: %w", err)
+			}
+			return m.Levels(), nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return Levels{}, fmt.Errorf("reading WAV: no data chunk")
+			}
+		}
+	}
+}
+
+// analyzeWithSoX decodes any format sox understands to raw PCM and meters it.
+func analyzeWithSoX(path string) (Levels, error) {
+	if _, err := exec.LookPath("sox"); err != nil {
+		return Levels{}, fmt.Errorf("sox is needed to check non-WAV audio\n\nInstall with:\n  macOS:  brew install sox\n  Linux:  sudo apt-get install sox")
+	}
+	m := newMeter(nil)
+	var stderr bytes.Buffer
+	cmd := exec.Command("sox", path, "-t", "raw", "-e", "signed-integer", "-b", "16", "-L", "-c", "1", "-r", "16000", "-")
+	cmd.Stdout = m
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Levels{}, fmt.Errorf("decoding %s: %s: %w", path, bytes.TrimSpace(stderr.Bytes()), err)
+	}
+	return m.Levels(), nil
+}
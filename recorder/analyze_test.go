@@ -0,0 +1,75 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestWAV writes pcm to a WAV file in dir and returns its path.
+func writeTestWAV(t *testing.T, pcm []byte) string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := newWAVWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(pcm)
+	if err := w.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name         string
+		pcm          []byte
+		wantSilent   bool
+		wantClipping bool
+	}{
+		{"digital silence", make([]byte, 2*SampleRate), true, false},
+		{"muted mic hiss", sinePCM(SampleRate, 0.002), true, false},
+		{"speech level", sinePCM(SampleRate, 0.3), false, false},
+		{"clipped", sinePCM(SampleRate, 1.5), false, true},
+		{"empty", nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Analyze(writeTestWAV(t, tt.pcm))
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if l.Silent() != tt.wantSilent {
+				t.Errorf("Silent() = %v, want %v (peak %.1f dBFS)", l.Silent(), tt.wantSilent, l.PeakDBFS)
+			}
+			if l.Clipping() != tt.wantClipping {
+				t.Errorf("Clipping() = %v, want %v (%.2f%% clipped)", l.Clipping(), tt.wantClipping, 100*l.ClippedFraction())
+			}
+		})
+	}
+}
+
+func TestAnalyzeMissingFile(t *testing.T) {
+	if _, err := Analyze("/nonexistent/audio.wav"); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestAnalyzeWAVHugeFmtChunk(t *testing.T) {
+	// A fmt chunk claiming 4GB must not be allocated up front.
+	var b bytes.Buffer
+	b.WriteString("RIFF\x00\x00\x00\x00WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(0xFFFFFFF0))
+	binary.Write(&b, binary.LittleEndian, [8]uint16{1, 1, 0, 0, 0, 0, 2, 16})
+	if _, err := analyzeWAV(&b); !errors.Is(err, errNotPCM16) {
+		t.Fatalf("analyzeWAV: got %v, want errNotPCM16", err)
+	}
+}
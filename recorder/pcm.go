@@ -0,0 +1,109 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PCM records with a tool that writes raw 16kHz, mono, signed 16-bit
+// little-endian PCM to stdout, such as rec, arecord or parec. vox writes
+// the WAV header itself and draws a level meter on stderr while recording.
+type PCM struct {
+	Command string
+	Args    []string
+}
+
+// Name returns the capture command.
+func (p PCM) Name() string { return p.Command }
+
+// Record captures audio until the context is cancelled.
+// The caller is responsible for deleting the temporary WAV file when done.
+func (p PCM) Record(ctx context.Context, opts Options) (Result, error) {
+	if _, err := exec.LookPath(p.Command); err != nil {
+		return Result{}, fmt.Errorf("%s not found on PATH", p.Command)
+	}
+
+	tmpFile, err := os.CreateTemp("", "vox-*.wav")
+	if err != nil {
+		return Result{}, fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	fail := func(err error) (Result, error) {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return Result{}, err
+	}
+
+	wav, err := newWAVWriter(tmpFile)
+	if err != nil {
+		return fail(fmt.Errorf("writing WAV header: %w", err))
+	}
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+
+	// Stop the capture tool politely; the WAV header is finalized here, not by it.
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 3 * time.Second
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fail(fmt.Errorf("getting stdout pipe: %w", err))
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fail(fmt.Errorf("starting %s: %w", p.Command, err))
+	}
+
+	m := newMeter(os.Stderr)
+	ready := &readyWriter{fn: opts.Ready}
+	_, copyErr := io.Copy(io.MultiWriter(ready, wav, m), stdout)
+	waitErr := cmd.Wait()
+	m.finish()
+
+	// A tool that exits on its own (rather than being cancelled) failed,
+	// e.g. because no capture device is available.
+	if waitErr != nil && ctx.Err() == nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = waitErr.Error()
+		}
+		return fail(fmt.Errorf("%s: %s", p.Command, msg))
+	}
+	if copyErr != nil {
+		return fail(fmt.Errorf("reading audio from %s: %w", p.Command, copyErr))
+	}
+	if err := wav.Finish(); err != nil {
+		return fail(fmt.Errorf("finalizing WAV header: %w", err))
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return Result{}, err
+	}
+
+	return Result{FilePath: tmpPath, Duration: wav.Duration(), Levels: m.Levels()}, nil
+}
+
+// readyWriter calls fn on the first non-empty write. It sits first in the
+// writer chain so the callback runs before any audio is metered.
+type readyWriter struct {
+	once sync.Once
+	fn   func()
+}
+
+func (r *readyWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && r.fn != nil {
+		r.once.Do(r.fn)
+	}
+	return len(p), nil
+}
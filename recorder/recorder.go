@@ -1,9 +1,12 @@
-// Package recorder wraps SoX rec for audio capture.
+// Package recorder captures microphone audio to a temporary WAV file.
+//
+// The default backends (SoX rec, arecord, parec) stream raw 16kHz, mono,
+// 16-bit PCM to stdout. vox meters the stream as it arrives and writes the
+// WAV header itself. The SoX backend, chosen by name only, lets rec write
+// the WAV file.
 package recorder
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -15,85 +18,77 @@ import (
 type Result struct {
 	FilePath string
 	Duration time.Duration
+	Levels   Levels // peak and average level of the whole recording
 }
 
-// Record captures audio using SoX rec until the context is cancelled.
-// The caller is responsible for deleting the temporary WAV file when done.
-func Record(ctx context.Context) (Result, error) {
-	if _, err := exec.LookPath("rec"); err != nil {
-		return Result{}, fmt.Errorf("rec (SoX) not found\n\nInstall with:\n  macOS:  brew install sox\n  Linux:  sudo apt-get install sox")
-	}
-
-	tmpFile, err := os.CreateTemp("", "vox-*.wav")
-	if err != nil {
-		return Result{}, fmt.Errorf("creating temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	tmpFile.Close()
-
-	cmd := exec.CommandContext(ctx, "rec", "-S", "-r", "16000", "-c", "1", "-b", "16", tmpPath)
-
-	// Send SIGINT instead of SIGKILL so rec can finalize the WAV header.
-	cmd.Cancel = func() error {
-		return cmd.Process.Signal(os.Interrupt)
-	}
-	cmd.WaitDelay = 3 * time.Second
+// Options tune a recording session.
+type Options struct {
+	// Ready, if set, is called once when the capture tool delivers its
+	// first audio. Capture tools take a moment to open the device, so
+	// callers should wait for Ready before telling the user to speak;
+	// otherwise the first syllable is lost.
+	Ready func()
+}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		os.Remove(tmpPath)
-		return Result{}, fmt.Errorf("getting stderr pipe: %w", err)
-	}
+// Recorder captures audio until its context is cancelled.
+type Recorder interface {
+	// Name returns the name of the capture tool the recorder shells out to.
+	Name() string
+	// Record captures audio into a temporary WAV file. The caller is
+	// responsible for deleting the file when done.
+	Record(ctx context.Context, opts Options) (Result, error)
+}
 
-	start := time.Now()
+// backends lists the supported recorders in order of preference.
+var backends = []Recorder{
+	PCM{Command: "rec", Args: []string{"-q", "-t", "raw", "-e", "signed-integer", "-b", "16", "-L", "-r", "16000", "-c", "1", "-"}},
+	PCM{Command: "arecord", Args: []string{"-q", "-t", "raw", "-f", "S16_LE", "-r", "16000", "-c", "1"}},
+	PCM{Command: "parec", Args: []string{"--raw", "--format=s16le", "--rate=16000", "--channels=1"}},
+}
 
-	if err := cmd.Start(); err != nil {
-		os.Remove(tmpPath)
-		return Result{}, fmt.Errorf("starting rec: %w", err)
+// Detect returns the first recorder whose capture tool is installed.
+// Setting $VOX_RECORDER to a backend name (rec, arecord, parec, sox) forces
+// that backend.
+func Detect() (Recorder, error) {
+	if name := os.Getenv("VOX_RECORDER"); name != "" {
+		return Lookup(name)
 	}
-
-	// Read stderr in a goroutine to display volume meter.
-	// SoX progress uses \r (not \n) between updates, so we split on both.
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		scanner.Split(scanCRLF)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if level, ok := parseVolume(line); ok {
-				bar := renderBar(level, 30)
-				fmt.Fprintf(os.Stderr, "\r  %s", bar)
-			}
+	for _, r := range backends {
+		if _, err := exec.LookPath(r.Name()); err == nil {
+			return r, nil
 		}
-	}()
-
-	_ = cmd.Wait()
-	elapsed := time.Since(start)
-
-	// Clear the volume bar line.
-	fmt.Fprintln(os.Stderr)
-
-	return Result{FilePath: tmpPath, Duration: elapsed}, nil
+	}
+	return nil, fmt.Errorf("no audio recorder found\n\nInstall with:\n  macOS:  brew install sox\n  Linux:  sudo apt-get install sox (or alsa-utils)")
 }
 
-// scanCRLF is a bufio.SplitFunc that splits on \n or \r.
-// SoX progress output uses \r between updates and \n for headers.
-func scanCRLF(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF && len(data) == 0 {
-		return 0, nil, nil
+// Lookup returns the named recorder: a capture tool, or sox for SoX rec
+// writing the WAV itself. It fails if the name is unknown or the tool is
+// not installed.
+func Lookup(name string) (Recorder, error) {
+	if name == "sox" {
+		if _, err := exec.LookPath("rec"); err != nil {
+			return nil, fmt.Errorf("rec (SoX) not found on PATH")
+		}
+		return SoX{}, nil
 	}
-	// Find earliest \r or \n.
-	cr := bytes.IndexByte(data, '\r')
-	lf := bytes.IndexByte(data, '\n')
-
-	switch {
-	case cr >= 0 && (lf < 0 || cr < lf):
-		return cr + 1, data[:cr], nil
-	case lf >= 0:
-		return lf + 1, data[:lf], nil
+	for _, r := range backends {
+		if r.Name() != name {
+			continue
+		}
+		if _, err := exec.LookPath(name); err != nil {
+			return nil, fmt.Errorf("%s not found on PATH", name)
+		}
+		return r, nil
 	}
+	return nil, fmt.Errorf("unknown recorder %q (want rec, arecord, parec or sox)", name)
+}
 
-	if atEOF {
-		return len(data), data, nil
+// Record captures audio with the detected recorder until the context is cancelled.
+// The caller is responsible for deleting the temporary WAV file when done.
+func Record(ctx context.Context, opts Options) (Result, error) {
+	r, err := Detect()
+	if err != nil {
+		return Result{}, err
 	}
-	return 0, nil, nil // request more data
+	return r.Record(ctx, opts)
 }
@@ -1,6 +1,7 @@
 package recorder
 
 import (
+	"math"
 	"os/exec"
 	"strings"
 	"testing"
@@ -78,6 +79,81 @@ func TestParseVolume(t *testing.T) {
 	}
 }
 
+func TestDBFS(t *testing.T) {
+	tests := []struct {
+		amplitude float64
+		want      float64
+	}{
+		{1.0, 0},
+		{0.5, -6.02},
+		{0.1, -20},
+		{0, floorDBFS},
+		{1e-9, floorDBFS},
+	}
+	for _, tt := range tests {
+		if got := dBFS(tt.amplitude); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("dBFS(%v) = %.2f, want %.2f", tt.amplitude, got, tt.want)
+		}
+	}
+}
+
+func TestMeterLevels(t *testing.T) {
+	tests := []struct {
+		name        string
+		pcm         []byte
+		wantPeak    float64
+		wantRMS     float64
+		wantClipped bool
+	}{
+		{"silence", make([]byte, 2*SampleRate), floorDBFS, floorDBFS, false},
+		{"half-scale sine", sinePCM(SampleRate, 0.5), -6.02, -9.03, false},
+		{"clipped sine", sinePCM(SampleRate, 1.0), 0, -3.01, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMeter(nil)
+			// Odd-sized writes split samples across calls.
+			for p := tt.pcm; len(p) > 0; {
+				n := min(len(p), 333)
+				m.Write(p[:n])
+				p = p[n:]
+			}
+			l := m.Levels()
+			if l.Samples != len(tt.pcm)/2 {
+				t.Errorf("Samples: got %d, want %d", l.Samples, len(tt.pcm)/2)
+			}
+			if math.Abs(l.PeakDBFS-tt.wantPeak) > 0.1 {
+				t.Errorf("PeakDBFS: got %.2f, want %.2f", l.PeakDBFS, tt.wantPeak)
+			}
+			if math.Abs(l.RMSDBFS-tt.wantRMS) > 0.1 {
+				t.Errorf("RMSDBFS: got %.2f, want %.2f", l.RMSDBFS, tt.wantRMS)
+			}
+			if (l.Clipped > 0) != tt.wantClipped {
+				t.Errorf("Clipped: got %d, want clipping=%v", l.Clipped, tt.wantClipped)
+			}
+		})
+	}
+}
+
+func TestMeterDraw(t *testing.T) {
+	var out strings.Builder
+	m := newMeter(&out)
+	m.Write(sinePCM(meterWindow*3, 1.0))
+	m.finish()
+
+	got := out.String()
+	if n := strings.Count(got, "\r  "); n != 3 {
+		t.Errorf("expected 3 meter updates, got %d", n)
+	}
+	if !strings.Contains(got, "CLIP") {
+		t.Errorf("expected clipping warning, got %q", got)
+	}
+	// \r\n, which also ends the line in raw mode.
+	if !strings.HasSuffix(got, "\r\n") {
+		t.Error("expected finish to end the meter line")
+	}
+}
+
 func TestRenderBar(t *testing.T) {
 	tests := []struct {
 		name       string
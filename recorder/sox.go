@@ -0,0 +1,177 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SoX records with SoX rec, which writes and finalizes the WAV file itself.
+// Its meter is scraped from rec's -S progress output, so it is coarser than
+// the PCM backends'. It is used only when the recorder setting is "sox",
+// for a rec that can't stream raw PCM.
+type SoX struct{}
+
+// Name returns "sox", the recorder setting that selects it. The capture
+// tool is rec.
+func (SoX) Name() string { return "sox" }
+
+// Record captures audio using SoX rec until the context is cancelled.
+// The caller is responsible for deleting the temporary WAV file when done.
+func (SoX) Record(ctx context.Context, opts Options) (Result, error) {
+	if _, err := exec.LookPath("rec"); err != nil {
+		return Result{}, fmt.Errorf("rec (SoX) not found\n\nInstall with:\n  macOS:  brew install sox\n  Linux:  sudo apt-get install sox")
+	}
+
+	tmpFile, err := os.CreateTemp("", "vox-*.wav")
+	if err != nil {
+		return Result{}, fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+
+	cmd := exec.CommandContext(ctx, "rec", "-S", "-r", "16000", "-c", "1", "-b", "16", tmpPath)
+
+	// Send SIGINT instead of SIGKILL so rec can finalize the WAV header.
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 3 * time.Second
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		os.Remove(tmpPath)
+		return Result{}, fmt.Errorf("getting stderr pipe: %w", err)
+	}
+
+	start := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		os.Remove(tmpPath)
+		return Result{}, fmt.Errorf("starting rec: %w", err)
+	}
+
+	// Read stderr to display the volume meter. SoX progress uses \r (not
+	// \n) between updates, so we split on both. The first meter line means
+	// audio is flowing. Other lines are kept for the error message.
+	var once sync.Once
+	var other []string
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanCRLF)
+	for scanner.Scan() {
+		line := scanner.Text()
+		level, ok := parseVolume(line)
+		if !ok {
+			if line = strings.TrimSpace(line); line != "" {
+				other = append(other, line)
+			}
+			continue
+		}
+		if opts.Ready != nil {
+			once.Do(opts.Ready)
+		}
+		fmt.Fprintf(os.Stderr, "\r  %s", renderBar(level, 30))
+	}
+
+	waitErr := cmd.Wait()
+	elapsed := time.Since(start)
+
+	// End the volume bar line; \r\n as for the PCM meter, since the
+	// terminal may be in raw mode.
+	fmt.Fprint(os.Stderr, "\r\n")
+
+	// rec exiting on its own (rather than being cancelled) failed.
+	if waitErr != nil && ctx.Err() == nil {
+		os.Remove(tmpPath)
+		msg := waitErr.Error()
+		if len(other) > 0 {
+			msg = other[len(other)-1]
+		}
+		return Result{}, fmt.Errorf("rec: %s", msg)
+	}
+
+	// rec's meter isn't exact, so measure the levels from the file.
+	levels, _ := Analyze(tmpPath)
+	return Result{FilePath: tmpPath, Duration: elapsed, Levels: levels}, nil
+}
+
+// scanCRLF is a bufio.SplitFunc that splits on \n or \r.
+// SoX progress output uses \r between updates and \n for headers.
+func scanCRLF(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	// Find earliest \r or \n.
+	cr := bytes.IndexByte(data, '\r')
+	lf := bytes.IndexByte(data, '\n')
+
+	switch {
+	case cr >= 0 && (lf < 0 || cr < lf):
+		return cr + 1, data[:cr], nil
+	case lf >= 0:
+		return lf + 1, data[:lf], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil // request more data
+}
+
+// parseVolume extracts a linear volume level (0.0–1.0) from a SoX -S progress line.
+// SoX outputs a VU meter like: [  ====|====  ] where = chars represent level.
+// In mono recording, both sides of the | mirror each other.
+func parseVolume(line string) (float64, bool) {
+	// Find the last VU meter bracket pair: [  ====|====  ]
+	// SoX progress lines contain multiple [...] groups; the VU meter is the one with |
+	lastOpen := -1
+	for i := len(line) - 1; i >= 0; i-- {
+		if line[i] == ']' {
+			// Find matching [
+			for j := i - 1; j >= 0; j-- {
+				if line[j] == '[' {
+					inner := line[j+1 : i]
+					if strings.Contains(inner, "|") {
+						lastOpen = j
+						break
+					}
+					break
+				}
+			}
+			if lastOpen >= 0 {
+				break
+			}
+		}
+	}
+	if lastOpen < 0 {
+		return 0, false
+	}
+
+	// Extract the bracket content after lastOpen
+	closeIdx := strings.Index(line[lastOpen:], "]")
+	if closeIdx < 0 {
+		return 0, false
+	}
+	inner := line[lastOpen+1 : lastOpen+closeIdx]
+
+	// Split on | to get left channel
+	pipeIdx := strings.Index(inner, "|")
+	if pipeIdx < 0 {
+		return 0, false
+	}
+	left := inner[:pipeIdx]
+
+	// Count = chars (signal) vs total width of the half
+	width := len(left)
+	if width == 0 {
+		return 0, false
+	}
+	filled := strings.Count(left, "=")
+	return float64(filled) / float64(width), true
+}
@@ -1,59 +1,158 @@
 package recorder
 
 import (
+	"fmt"
+	"io"
+	"math"
 	"strings"
 )
 
-// parseVolume extracts a linear volume level (0.0–1.0) from a SoX -S progress line.
-// SoX outputs a VU meter like: [  ====|====  ] where = chars represent level.
-// In mono recording, both sides of the | mirror each other.
-func parseVolume(line string) (float64, bool) {
-	// Find the last VU meter bracket pair: [  ====|====  ]
-	// SoX progress lines contain multiple [...] groups; the VU meter is the one with |
-	lastOpen := -1
-	for i := len(line) - 1; i >= 0; i-- {
-		if line[i] == ']' {
-			// Find matching [
-			for j := i - 1; j >= 0; j-- {
-				if line[j] == '[' {
-					inner := line[j+1 : i]
-					if strings.Contains(inner, "|") {
-						lastOpen = j
-						break
-					}
-					break
-				}
-			}
-			if lastOpen >= 0 {
-				break
-			}
+const (
+	// floorDBFS is the level reported for digital silence: the dynamic
+	// range of 16-bit audio.
+	floorDBFS = -96.0
+	// meterRangeDB is the span of the on-screen meter, from -meterRangeDB to 0 dBFS.
+	meterRangeDB = 60.0
+	// meterWindow is the number of samples per meter update (50ms).
+	meterWindow = SampleRate / 20
+	// clipHold is how many meter updates the clipping warning stays visible.
+	clipHold = 20
+)
+
+// Levels summarizes the signal level of a stretch of audio.
+type Levels struct {
+	PeakDBFS float64 // loudest sample
+	RMSDBFS  float64 // average (RMS) level
+	Clipped  int     // samples at full scale
+	Samples  int
+}
+
+// levelAccumulator computes peak and RMS over 16-bit little-endian PCM
+// delivered in arbitrary byte slices.
+type levelAccumulator struct {
+	sumSq   float64
+	peak    int
+	clipped int
+	n       int
+}
+
+func (a *levelAccumulator) add(s int16) {
+	v := int(s)
+	if v < 0 {
+		v = -v
+	}
+	if v > a.peak {
+		a.peak = v
+	}
+	if v >= math.MaxInt16 {
+		a.clipped++
+	}
+	f := float64(s) / 32768
+	a.sumSq += f * f
+	a.n++
+}
+
+func (a *levelAccumulator) levels() Levels {
+	l := Levels{PeakDBFS: floorDBFS, RMSDBFS: floorDBFS, Clipped: a.clipped, Samples: a.n}
+	if a.n == 0 {
+		return l
+	}
+	l.PeakDBFS = dBFS(float64(a.peak) / 32768)
+	l.RMSDBFS = dBFS(math.Sqrt(a.sumSq / float64(a.n)))
+	return l
+}
+
+// dBFS converts a linear amplitude (0.0–1.0) to decibels relative to full scale.
+func dBFS(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return floorDBFS
+	}
+	return math.Max(20*math.Log10(amplitude), floorDBFS)
+}
+
+// meter is an io.Writer that consumes PCM, tracks levels for the whole
+// recording and, if out is non-nil, draws a live level meter to it.
+type meter struct {
+	out   io.Writer
+	total levelAccumulator
+	win   levelAccumulator
+
+	carry    []byte  // odd trailing byte from the previous Write
+	level    float64 // smoothed meter position, 0.0–1.0
+	clipShow int     // remaining updates to show the clipping warning
+}
+
+func newMeter(out io.Writer) *meter {
+	return &meter{out: out}
+}
+
+// Write feeds PCM samples to the meter. It never fails.
+func (m *meter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(m.carry) > 0 {
+		p = append(m.carry, p...)
+		m.carry = nil
+	}
+	for len(p) >= 2 {
+		s := int16(uint16(p[0]) | uint16(p[1])<<8)
+		m.total.add(s)
+		m.win.add(s)
+		if m.win.n == meterWindow {
+			m.draw()
+			m.win = levelAccumulator{}
 		}
+		p = p[2:]
 	}
-	if lastOpen < 0 {
-		return 0, false
+	if len(p) == 1 {
+		m.carry = []byte{p[0]}
 	}
+	return n, nil
+}
 
-	// Extract the bracket content after lastOpen
-	closeIdx := strings.Index(line[lastOpen:], "]")
-	if closeIdx < 0 {
-		return 0, false
+// draw renders one meter update from the current window.
+func (m *meter) draw() {
+	if m.out == nil {
+		return
+	}
+	w := m.win.levels()
+	target := (w.RMSDBFS + meterRangeDB) / meterRangeDB
+	// Fast attack, slow release, so the bar tracks speech without flicker.
+	if target > m.level {
+		m.level = target
+	} else {
+		m.level = 0.8*m.level + 0.2*target
 	}
-	inner := line[lastOpen+1 : lastOpen+closeIdx]
+	if w.Clipped > 0 {
+		m.clipShow = clipHold
+	} else if m.clipShow > 0 {
+		m.clipShow--
+	}
+	fmt.Fprintf(m.out, "\r  %s", renderMeter(m.level, w.PeakDBFS, m.clipShow > 0))
+}
 
-	// Split on | to get left channel
-	pipeIdx := strings.Index(inner, "|")
-	if pipeIdx < 0 {
-		return 0, false
+// finish ends the meter line. It writes \r\n because vox --ptt keeps the
+// terminal in raw mode while recording, where a bare \n doesn't return
+// to the first column.
+func (m *meter) finish() {
+	if m.out != nil {
+		fmt.Fprint(m.out, "\r\n")
 	}
-	left := inner[:pipeIdx]
+}
+
+// Levels returns the levels of everything written so far.
+func (m *meter) Levels() Levels {
+	return m.total.levels()
+}
 
-	// Count = chars (signal) vs total width of the half
-	width := len(left)
-	if width == 0 {
-		return 0, false
+// renderMeter formats a meter line: the bar, the window peak and an
+// optional clipping warning. The line has a fixed width so that \r
+// redraws fully overwrite the previous one.
+func renderMeter(level, peakDBFS float64, clipping bool) string {
+	warn := "         "
+	if clipping {
+		warn = " ⚠ CLIP  "
 	}
-	filled := strings.Count(left, "=")
-	return float64(filled) / float64(width), true
+	return fmt.Sprintf("%s %4.0f dB%s", renderBar(level, 30), math.Max(peakDBFS, -99), warn)
 }
 
 // renderBar renders a volume bar of the given width using block characters.
@@ -0,0 +1,89 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Audio format shared by every backend.
+const (
+	SampleRate    = 16000
+	Channels      = 1
+	BitsPerSample = 16
+
+	bytesPerSecond = SampleRate * Channels * BitsPerSample / 8
+	wavHeaderSize  = 44
+)
+
+// wavWriter streams PCM data into a canonical 44-byte-header WAV file.
+// The header is written with zero sizes up front and patched by Finish.
+type wavWriter struct {
+	w io.WriteSeeker
+	n int64 // PCM bytes written
+}
+
+// newWAVWriter writes a placeholder header to w and returns a writer for
+// the PCM payload.
+func newWAVWriter(w io.WriteSeeker) (*wavWriter, error) {
+	if err := writeWAVHeader(w, 0); err != nil {
+		return nil, err
+	}
+	return &wavWriter{w: w}, nil
+}
+
+// Write appends raw PCM samples.
+func (w *wavWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Finish pads a trailing half sample and rewrites the header with the
+// final sizes. It does not close the underlying writer.
+func (w *wavWriter) Finish() error {
+	if w.n%2 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeWAVHeader(w.w, uint32(w.n)); err != nil {
+		return err
+	}
+	_, err := w.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// Duration returns the length of the audio written so far.
+func (w *wavWriter) Duration() time.Duration {
+	return time.Duration(w.n) * time.Second / bytesPerSecond
+}
+
+// writeWAVHeader writes a RIFF/WAVE header for dataSize bytes of PCM in
+// the package's fixed audio format.
+func writeWAVHeader(w io.Writer, dataSize uint32) error {
+	var h [wavHeaderSize]byte
+	le := binary.LittleEndian
+
+	copy(h[0:], "RIFF")
+	le.PutUint32(h[4:], 36+dataSize)
+	copy(h[8:], "WAVE")
+
+	copy(h[12:], "fmt ")
+	le.PutUint32(h[16:], 16) // fmt chunk size
+	le.PutUint16(h[20:], 1)  // PCM
+	le.PutUint16(h[22:], Channels)
+	le.PutUint32(h[24:], SampleRate)
+	le.PutUint32(h[28:], bytesPerSecond)
+	le.PutUint16(h[32:], Channels*BitsPerSample/8) // block align
+	le.PutUint16(h[34:], BitsPerSample)
+
+	copy(h[36:], "data")
+	le.PutUint32(h[40:], dataSize)
+
+	_, err := w.Write(h[:])
+	return err
+}
@@ -0,0 +1,159 @@
+package recorder
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sinePCM returns n samples of a 440Hz sine wave as 16-bit little-endian PCM.
+func sinePCM(n int, amplitude float64) []byte {
+	buf := make([]byte, 2*n)
+	for i := range n {
+		v := amplitude * math.Sin(2*math.Pi*440*float64(i)/SampleRate)
+		v = math.Max(-1, math.Min(1, v)) // amplitudes above 1 clip like an overdriven input
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(int16(v*math.MaxInt16)))
+	}
+	return buf
+}
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcm := sinePCM(SampleRate/2, 0.5) // 0.5s
+	w, err := newWAVWriter(f)
+	if err != nil {
+		t.Fatalf("newWAVWriter: %v", err)
+	}
+	// Write in uneven pieces, as a pipe would deliver them.
+	for _, part := range [][]byte{pcm[:1001], pcm[1001:7777], pcm[7777:]} {
+		if _, err := w.Write(part); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Finish(); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	f.Close()
+
+	if got := w.Duration(); got != 500*time.Millisecond {
+		t.Errorf("Duration: got %v, want 500ms", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != wavHeaderSize+len(pcm) {
+		t.Fatalf("file size: got %d, want %d", len(data), wavHeaderSize+len(pcm))
+	}
+
+	le := binary.LittleEndian
+	checks := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"riff size", le.Uint32(data[4:]), uint32(36 + len(pcm))},
+		{"format", uint32(le.Uint16(data[20:])), 1},
+		{"channels", uint32(le.Uint16(data[22:])), Channels},
+		{"sample rate", le.Uint32(data[24:]), SampleRate},
+		{"byte rate", le.Uint32(data[28:]), bytesPerSecond},
+		{"bits per sample", uint32(le.Uint16(data[34:])), BitsPerSample},
+		{"data size", le.Uint32(data[40:]), uint32(len(pcm))},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, c.got, c.want)
+		}
+	}
+	for _, tag := range []struct {
+		off  int
+		want string
+	}{{0, "RIFF"}, {8, "WAVE"}, {12, "fmt "}, {36, "data"}} {
+		if got := string(data[tag.off : tag.off+4]); got != tag.want {
+			t.Errorf("chunk id at %d: got %q, want %q", tag.off, got, tag.want)
+		}
+	}
+	if string(data[wavHeaderSize:]) != string(pcm) {
+		t.Error("PCM payload does not match input")
+	}
+}
+
+func TestWAVWriterPadsHalfSample(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "odd.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w, err := newWAVWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte{1, 2, 3})
+	if err := w.Finish(); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	info, _ := f.Stat()
+	if info.Size() != wavHeaderSize+4 {
+		t.Errorf("file size: got %d, want %d", info.Size(), wavHeaderSize+4)
+	}
+}
+
+func TestPCMRecord(t *testing.T) {
+	// cat stands in for arecord: it streams raw PCM to stdout and exits.
+	src := filepath.Join(t.TempDir(), "in.raw")
+	pcm := sinePCM(SampleRate, 0.5)
+	if err := os.WriteFile(src, pcm, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	readyCalls := 0
+	res, err := PCM{Command: "cat", Args: []string{src}}.Record(context.Background(), Options{
+		Ready: func() { readyCalls++ },
+	})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	defer os.Remove(res.FilePath)
+
+	if readyCalls != 1 {
+		t.Errorf("Ready called %d times, want 1", readyCalls)
+	}
+	if res.Duration != time.Second {
+		t.Errorf("Duration: got %v, want 1s", res.Duration)
+	}
+	info, err := os.Stat(res.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(wavHeaderSize+len(pcm)) {
+		t.Errorf("file size: got %d, want %d", info.Size(), wavHeaderSize+len(pcm))
+	}
+}
+
+func TestPCMRecordToolFails(t *testing.T) {
+	ready := false
+	_, err := PCM{Command: "false"}.Record(context.Background(), Options{Ready: func() { ready = true }})
+	if err == nil {
+		t.Fatal("expected error when capture tool exits with failure")
+	}
+	if ready {
+		t.Error("Ready called although no audio was produced")
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	if _, err := Lookup("nosuchrecorder"); err == nil {
+		t.Fatal("expected error for unknown recorder")
+	}
+}
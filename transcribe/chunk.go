@@ -9,6 +9,7 @@ import (
 	"strings"
 )
 
+// Defaults for the chunk_seconds and chunk_threshold settings.
 const (
 	// ChunkDuration is the length of each chunk in seconds (5 minutes).
 	ChunkDuration = 300.0
@@ -30,10 +31,10 @@ func GetDuration(filePath string) (float64, error) {
 	return dur, nil
 }
 
-// ChunkFile splits an audio file into segments of ChunkDuration seconds
+// ChunkFile splits an audio file into segments of chunkSeconds seconds
 // using sox trim. Returns a list of temporary file paths; caller must clean up.
 // The totalDuration parameter avoids re-reading duration.
-func ChunkFile(filePath string, totalDuration float64) ([]string, error) {
+func ChunkFile(filePath string, totalDuration, chunkSeconds float64) ([]string, error) {
 	ext := filepath.Ext(filePath)
 	if ext == "" {
 		ext = ".wav"
@@ -43,14 +44,14 @@ func ChunkFile(filePath string, totalDuration float64) ([]string, error) {
 	base := strings.TrimSuffix(filepath.Base(filePath), ext)
 
 	var chunks []string
-	for start := 0.0; start < totalDuration; start += ChunkDuration {
+	for start := 0.0; start < totalDuration; start += chunkSeconds {
 		idx := len(chunks)
 		outPath := filepath.Join(dir, fmt.Sprintf("%s_chunk%03d%s", base, idx, ext))
 
 		// sox input output trim <start> <duration>
 		args := []string{filePath, outPath, "trim",
 			strconv.FormatFloat(start, 'f', 2, 64),
-			strconv.FormatFloat(ChunkDuration, 'f', 2, 64),
+			strconv.FormatFloat(chunkSeconds, 'f', 2, 64),
 		}
 		cmd := exec.Command("sox", args...)
 		if out, err := cmd.CombinedOutput(); err != nil {
@@ -40,7 +40,7 @@ func TestChunkFileShort(t *testing.T) {
 	}
 
 	// ChunkFile with a short duration should still produce one chunk.
-	chunks, err := ChunkFile(tmp, dur)
+	chunks, err := ChunkFile(tmp, dur, ChunkDuration)
 	if err != nil {
 		t.Fatalf("ChunkFile: %v", err)
 	}
@@ -64,7 +64,7 @@ func TestChunkFileMultiple(t *testing.T) {
 		t.Fatalf("GetDuration: %v", err)
 	}
 
-	chunks, err := ChunkFile(tmp, dur)
+	chunks, err := ChunkFile(tmp, dur, ChunkDuration)
 	if err != nil {
 		t.Fatalf("ChunkFile: %v", err)
 	}
@@ -0,0 +1,78 @@
+package transcribe
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cdimoush/vox/config"
+)
+
+// Prompt returns the prompt sent with the audio: the prompt setting,
+// followed by the vocabulary.
+func Prompt(s config.Settings) string {
+	prompt := strings.TrimSpace(s.Prompt)
+	if len(s.Vocabulary) > 0 {
+		prompt = strings.TrimSpace(prompt + "\nVocabulary: " + strings.Join(s.Vocabulary, ", ") + ".")
+	}
+	return prompt
+}
+
+// fillers matches a filler word and the comma or space after it.
+var fillers = regexp.MustCompile(`(?i)\b(?:u+m+|u+h+|e+r+m+|h+m+)\b,?\s*`)
+
+// spaceBeforePunct matches the space a filler leaves before punctuation.
+var spaceBeforePunct = regexp.MustCompile(`\s+([.,!?;:])`)
+
+// PostProcess applies the transcript settings to text, in order: filler
+// words are removed (strip_fillers), replace rewrites are applied, and
+// vocabulary terms are respelled exactly as configured.
+func PostProcess(text string, s config.Settings) string {
+	if s.StripFillers {
+		text = stripFillers(text)
+	}
+	for _, r := range s.Replace {
+		text = wholeWords(r.From).ReplaceAllLiteralString(text, r.To)
+	}
+	for _, term := range s.Vocabulary {
+		text = wholeWords(term).ReplaceAllLiteralString(text, term)
+	}
+	return text
+}
+
+// wholeWords matches phrase as whole words, in any case.
+func wholeWords(phrase string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(phrase)
+	if r, _ := utf8.DecodeRuneInString(phrase); isWordRune(r) {
+		expr = `\b` + expr
+	}
+	if r, _ := utf8.DecodeLastRuneInString(phrase); isWordRune(r) {
+		expr += `\b`
+	}
+	return regexp.MustCompile(`(?i)` + expr)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// stripFillers removes filler words, capitalizing what follows one that
+// started a sentence.
+func stripFillers(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range fillers.FindAllStringIndex(text, -1) {
+		b.WriteString(text[last:m[0]])
+		last = m[1]
+		capital, _ := utf8.DecodeRuneInString(text[m[0]:])
+		if unicode.IsUpper(capital) && last < len(text) {
+			r, size := utf8.DecodeRuneInString(text[last:])
+			b.WriteRune(unicode.ToUpper(r))
+			last += size
+		}
+	}
+	b.WriteString(text[last:])
+	text = spaceBeforePunct.ReplaceAllString(b.String(), "$1")
+	return strings.TrimLeft(strings.TrimSpace(text), ".,!?;: ")
+}
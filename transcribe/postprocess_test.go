@@ -0,0 +1,66 @@
+package transcribe
+
+import (
+	"testing"
+
+	"github.com/cdimoush/vox/config"
+)
+
+func TestPrompt(t *testing.T) {
+	tests := []struct {
+		s    config.Settings
+		want string
+	}{
+		{config.Settings{}, ""},
+		{config.Settings{Prompt: "Robotics standup."}, "Robotics standup."},
+		{config.Settings{Vocabulary: []string{"Isaac Sim", "USD"}}, "Vocabulary: Isaac Sim, USD."},
+		{config.Settings{Prompt: "Standup.", Vocabulary: []string{"USD"}}, "Standup.\nVocabulary: USD."},
+	}
+	for _, tt := range tests {
+		if got := Prompt(tt.s); got != tt.want {
+			t.Errorf("Prompt(%+v) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	tests := []struct {
+		name, text string
+		s          config.Settings
+		want       string
+	}{
+		{"nothing set", "Um, so the thing.", config.Settings{}, "Um, so the thing."},
+		{"fillers", "Um, so the thing, uh, works. Uhh the test umm.", config.Settings{StripFillers: true}, "So the thing, works. The test."},
+		{"filler-like words stay", "The umbrella and the hummus.", config.Settings{StripFillers: true}, "The umbrella and the hummus."},
+		{"only fillers", "Um.", config.Settings{StripFillers: true}, ""},
+		{
+			"replace",
+			"Push it to Vox Core, teh repo.",
+			config.Settings{Replace: []config.Replacement{{From: "vox core", To: "vox-core"}, {From: "teh", To: "the"}}},
+			"Push it to vox-core, the repo.",
+		},
+		{
+			"replace whole words only",
+			"Tehran is far.",
+			config.Settings{Replace: []config.Replacement{{From: "teh", To: "the"}}},
+			"Tehran is far.",
+		},
+		{
+			"vocabulary respelled",
+			"Open isaac sim and export the usd file, not USDA.",
+			config.Settings{Vocabulary: []string{"Isaac Sim", "USD"}},
+			"Open Isaac Sim and export the USD file, not USDA.",
+		},
+		{
+			"vocabulary with punctuation",
+			"We use c++ and node.js.",
+			config.Settings{Vocabulary: []string{"C++", "Node.js"}},
+			"We use C++ and Node.js.",
+		},
+	}
+	for _, tt := range tests {
+		if got := PostProcess(tt.text, tt.s); got != tt.want {
+			t.Errorf("%s: PostProcess(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
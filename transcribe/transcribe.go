@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 
@@ -17,18 +18,110 @@ var ErrNoAPIKey = errors.New("OpenAI API key not found — run: vox login")
 // ErrAPI is a sentinel for OpenAI API errors (rate limits, timeouts, server errors).
 var ErrAPI = errors.New("API error")
 
+// Provider and Model identify the transcription service vox uses by
+// default. The model setting picks another model.
+const (
+	Provider = "openai"
+	Model    = "gpt-4o-mini-transcribe"
+)
+
+// ConfiguredModel returns the model to transcribe with: the model
+// setting, or Model.
+func ConfiguredModel() string {
+	if m := config.Get("model"); m != "" {
+		return m
+	}
+	return Model
+}
+
+// newClient returns an API client for the base_url setting, which can
+// point at another OpenAI-compatible server, such as a local one.
+func newClient(apiKey string, s config.Settings) *openai.Client {
+	cfg := openai.DefaultConfig(apiKey)
+	if s.BaseURL != "" {
+		cfg.BaseURL = s.BaseURL
+	}
+	return openai.NewClientWithConfig(cfg)
+}
+
+// CheckAPI asks the configured endpoint for its list of models, to see
+// whether it can be reached and accepts the key. reached is true if the
+// server answered at all, even with an error such as a rejected key.
+func CheckAPI(ctx context.Context) (reached bool, err error) {
+	apiKey := config.FindAPIKey()
+	if apiKey == "" {
+		return false, ErrNoAPIKey
+	}
+	s, err := config.Load()
+	if err != nil {
+		return false, err
+	}
+	if _, err := newClient(apiKey, s).ListModels(ctx); err != nil {
+		// The HTTP client fails with a *url.Error when no response came back.
+		var urlErr *url.Error
+		return !errors.As(err, &urlErr), fmt.Errorf("%w: %w", ErrAPI, err)
+	}
+	return true, nil
+}
+
+// prices are OpenAI's list prices in US dollars per minute of audio.
+var prices = map[string]float64{
+	"gpt-4o-mini-transcribe": 0.003,
+	"gpt-4o-transcribe":      0.006,
+	"whisper-1":              0.006,
+}
+
+// Result is a finished transcription.
+type Result struct {
+	Text     string
+	Duration float64 // audio duration in seconds; 0 if it couldn't be measured
+	Chunks   int     // number of API requests the audio was sent in
+	Language string  // detected by the API (whisper-1 only), else the language setting; "" if neither
+}
+
+// verboseJSONModels accept response_format=verbose_json, the only format
+// that reports the detected language. The gpt-4o transcribe models answer
+// json or text only.
+var verboseJSONModels = map[string]bool{"whisper-1": true}
+
+// PricePerMinute returns the price in US dollars per minute of audio for
+// model: the price.<model> setting if there is a valid one, otherwise
+// the list price. ok is false for a model with no known price.
+func PricePerMinute(model string) (price float64, ok bool) {
+	if key := "price." + model; config.Get(key) != "" {
+		if p, err := config.Dollars(key); err == nil {
+			return p, true
+		}
+	}
+	price, ok = prices[model]
+	return price, ok
+}
+
+// EstimateCost returns the approximate price in US dollars of
+// transcribing seconds of audio with model, or 0 if its price is unknown.
+func EstimateCost(model string, seconds float64) float64 {
+	price, _ := PricePerMinute(model)
+	return seconds / 60 * price
+}
+
 // Transcribe sends the audio file at filePath to the OpenAI Whisper API
 // and returns the transcribed text and audio duration in seconds.
-// For files longer than 8 minutes, the audio is automatically chunked
-// into 5-minute segments and transcribed sequentially.
-func Transcribe(ctx context.Context, filePath string) (string, float64, error) {
+// Files longer than the chunk_threshold setting (8 minutes by default)
+// are split into chunk_seconds segments (5 minutes) and transcribed
+// sequentially. The transcript is then post-processed (see PostProcess).
+func Transcribe(ctx context.Context, filePath string) (Result, error) {
 	apiKey := config.FindAPIKey()
 	if apiKey == "" {
-		return "", 0, ErrNoAPIKey
+		return Result{}, ErrNoAPIKey
+	}
+	s, err := config.Load()
+	if err != nil {
+		return Result{}, err
 	}
+	client := newClient(apiKey, s)
 
 	if _, err := os.Stat(filePath); err != nil {
-		return "", 0, fmt.Errorf("audio file: %w", err)
+		return Result{}, fmt.Errorf("audio file: %w", err)
 	}
 
 	duration, err := GetDuration(filePath)
@@ -37,57 +130,82 @@ func Transcribe(ctx context.Context, filePath string) (string, float64, error) {
 		duration = 0
 	}
 
-	// Chunk if longer than 8 minutes.
-	if duration > ChunkThreshold {
-		return transcribeChunked(ctx, apiKey, filePath, duration)
+	if duration > s.ChunkThreshold {
+		result, err := transcribeChunked(ctx, client, s, filePath, duration)
+		if err == nil {
+			result.Text = PostProcess(result.Text, s)
+		}
+		return result, err
 	}
 
-	text, err := transcribeSingle(ctx, apiKey, filePath)
+	resp, err := transcribeSingle(ctx, client, s, filePath)
 	if err != nil {
-		return "", duration, err
+		return Result{Duration: duration}, err
 	}
-	return text, duration, nil
+	return Result{Text: PostProcess(resp.Text, s), Duration: duration, Chunks: 1, Language: resp.Language}, nil
 }
 
-// transcribeSingle transcribes a single audio file.
-func transcribeSingle(ctx context.Context, apiKey, filePath string) (string, error) {
-	client := openai.NewClient(apiKey)
-	resp, err := client.CreateTranscription(ctx, openai.AudioRequest{
-		Model:    "gpt-4o-mini-transcribe",
-		FilePath: filePath,
-	})
+// transcribeSingle transcribes a single audio file. The response's
+// Language is the detected language if the model reports one, else the
+// language setting.
+func transcribeSingle(ctx context.Context, client *openai.Client, s config.Settings, filePath string) (openai.AudioResponse, error) {
+	resp, err := client.CreateTranscription(ctx, audioRequest(s, filePath))
 	if err != nil {
-		return "", fmt.Errorf("%w: %w", ErrAPI, err)
+		return resp, fmt.Errorf("%w: %w", ErrAPI, err)
+	}
+	if resp.Language == "" {
+		resp.Language = s.Language
 	}
-	return resp.Text, nil
+	return resp, nil
+}
+
+// audioRequest builds the transcription request for filePath.
+func audioRequest(s config.Settings, filePath string) openai.AudioRequest {
+	req := openai.AudioRequest{
+		Model:    s.Model,
+		FilePath: filePath,
+		Language: s.Language,
+		Prompt:   Prompt(s),
+	}
+	if verboseJSONModels[s.Model] {
+		req.Format = openai.AudioResponseFormatVerboseJSON
+	}
+	return req
 }
 
 // transcribeChunked splits the file into chunks and transcribes each.
-func transcribeChunked(ctx context.Context, apiKey, filePath string, duration float64) (string, float64, error) {
-	chunks, err := ChunkFile(filePath, duration)
+func transcribeChunked(ctx context.Context, client *openai.Client, s config.Settings, filePath string, duration float64) (Result, error) {
+	result := Result{Duration: duration}
+	chunks, err := ChunkFile(filePath, duration, s.ChunkSeconds)
 	if err != nil {
-		return "", duration, fmt.Errorf("chunking audio: %w", err)
+		return result, fmt.Errorf("chunking audio: %w", err)
 	}
 	defer func() {
 		for _, c := range chunks {
 			os.Remove(c)
 		}
 	}()
+	result.Chunks = len(chunks)
 
 	var parts []string
 	for _, chunk := range chunks {
 		select {
 		case <-ctx.Done():
-			return "", duration, ctx.Err()
+			return result, ctx.Err()
 		default:
 		}
 
-		text, err := transcribeSingle(ctx, apiKey, chunk)
+		resp, err := transcribeSingle(ctx, client, s, chunk)
 		if err != nil {
-			return strings.Join(parts, " "), duration, err
+			result.Text = strings.Join(parts, " ")
+			return result, err
+		}
+		parts = append(parts, strings.TrimSpace(resp.Text))
+		if result.Language == "" {
+			result.Language = resp.Language
 		}
-		parts = append(parts, strings.TrimSpace(text))
 	}
 
-	return strings.Join(parts, " "), duration, nil
+	result.Text = strings.Join(parts, " ")
+	return result, nil
 }
@@ -3,13 +3,20 @@ package transcribe
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+
+	"github.com/cdimoush/vox/config"
+	openai "github.com/sashabaranov/go-openai"
 )
 
 func TestTranscribeMissingAPIKey(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "")
 	t.Setenv("HOME", t.TempDir()) // prevent fallback to ~/.bashrc or ~/.vox/config
-	_, _, err := Transcribe(context.Background(), "somefile.wav")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_, err := Transcribe(context.Background(), "somefile.wav")
 	if !errors.Is(err, ErrNoAPIKey) {
 		t.Fatalf("expected ErrNoAPIKey, got: %v", err)
 	}
@@ -17,8 +24,56 @@ func TestTranscribeMissingAPIKey(t *testing.T) {
 
 func TestTranscribeMissingFile(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "test-key-not-real")
-	_, _, err := Transcribe(context.Background(), "/nonexistent/path/audio.wav")
+	_, err := Transcribe(context.Background(), "/nonexistent/path/audio.wav")
 	if err == nil {
 		t.Fatal("expected error for missing file")
 	}
 }
+
+func TestAudioRequestFormat(t *testing.T) {
+	// Only whisper-1 is asked for verbose_json, which reports the language.
+	for model, want := range map[string]openai.AudioResponseFormat{
+		"whisper-1":              openai.AudioResponseFormatVerboseJSON,
+		"gpt-4o-mini-transcribe": "",
+		"gpt-4o-transcribe":      "",
+	} {
+		if got := audioRequest(config.Settings{Model: model}, "a.wav").Format; got != want {
+			t.Errorf("%s: Format = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if got := EstimateCost(Model, 0); got != 0 {
+		t.Errorf("EstimateCost(0) = %v", got)
+	}
+	if got := EstimateCost(Model, 600); got < 0.0299 || got > 0.0301 {
+		t.Errorf("EstimateCost(10 min) = %v, want 0.03", got)
+	}
+	if got := EstimateCost("no-such-model", 600); got != 0 {
+		t.Errorf("unknown model cost = %v, want 0", got)
+	}
+
+	os.MkdirAll(filepath.Join(home, ".vox"), 0o700)
+	os.WriteFile(filepath.Join(home, ".vox", "config"), []byte("price."+Model+"=0.01\n"), 0o600)
+	if got := EstimateCost(Model, 600); got < 0.0999 || got > 0.1001 {
+		t.Errorf("configured price: cost = %v, want 0.1", got)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	// The config package can't import this one, so it repeats the defaults.
+	for key, want := range map[string]string{
+		"model":           Model,
+		"chunk_seconds":   strconv.FormatFloat(ChunkDuration, 'f', -1, 64),
+		"chunk_threshold": strconv.FormatFloat(ChunkThreshold, 'f', -1, 64),
+	} {
+		if s, _ := config.Known(key); s.Default != want {
+			t.Errorf("config default for %s = %q, want %q", key, s.Default, want)
+		}
+	}
+}